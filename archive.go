@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"washwise/archive"
+	"washwise/model"
+)
+
+// runArchive 立即归档超过保留期的数据，不受 retention.enabled 影响
+func runArchive(configPath string, args []string) error {
+	fs := newFlagSet("archive", &configPath)
+	vacuum := fs.Bool("vacuum", false, "归档后回收 SQLite 数据库文件空间，执行期间会锁定数据库")
+	_ = fs.Parse(args)
+
+	cfg, err := setup(configPath)
+	if err != nil {
+		return err
+	}
+
+	result, err := archive.Run(time.Now())
+	for _, path := range result.Files {
+		fmt.Printf("已写入 %s\n", path)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已归档使用记录 %d 条（保留 %d 天），排队记录 %d 条（保留 %d 天）\n",
+		result.Usages, cfg.Retention.UsageDays, result.QueueEntries, cfg.Retention.QueueDays)
+
+	if *vacuum {
+		if err := model.Compact(); err != nil {
+			return fmt.Errorf("回收数据库空间失败: %w", err)
+		}
+		fmt.Println("已回收数据库空间")
+	}
+	return nil
+}
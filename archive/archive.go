@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"washwise/config"
+	"washwise/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Result 一次归档的结果
+type Result struct {
+	Usages       int64    // 归档并删除的使用记录数
+	QueueEntries int64    // 归档并删除的排队记录数
+	Files        []string // 写入的归档文件
+}
+
+// Archiver 定期归档超过保留期的数据
+type Archiver struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex // 避免归档任务重叠执行
+	ticker *time.Ticker
+}
+
+// New 创建归档任务
+func New() *Archiver {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Archiver{ctx: ctx, cancel: cancel}
+}
+
+// Start 启动归档任务，启动时执行一次，之后按 retention.interval 周期执行
+// 是否执行由 retention.enabled 决定，热重载后立即生效
+func (a *Archiver) Start() {
+	ticker := time.NewTicker(config.GetRetentionInterval())
+	a.ticker = ticker
+
+	go func() {
+		defer ticker.Stop()
+		a.runIfEnabled()
+		for {
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-ticker.C:
+				a.runIfEnabled()
+			}
+		}
+	}()
+}
+
+// ApplyConfig 应用重新加载的配置，调整归档周期
+func (a *Archiver) ApplyConfig(old, new *config.Config) {
+	if a.ticker != nil && old.Retention.Interval != new.Retention.Interval {
+		a.ticker.Reset(new.Retention.Interval.Duration())
+		log.WithField("interval", new.Retention.Interval).Info("归档周期已调整")
+	}
+}
+
+// Stop 停止归档任务，等待正在执行的归档完成
+func (a *Archiver) Stop() {
+	a.cancel()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+}
+
+func (a *Archiver) runIfEnabled() {
+	if !config.Get().Retention.Enabled {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ctx.Err() != nil {
+		return
+	}
+
+	begin := time.Now()
+	result, err := Run(begin)
+	if err != nil {
+		log.WithError(err).Error("归档数据失败")
+		return
+	}
+	log.WithFields(log.Fields{
+		"usages":        result.Usages,
+		"queue_entries": result.QueueEntries,
+		"files":         len(result.Files),
+		"cost":          time.Since(begin).Round(time.Millisecond),
+	}).Info("归档数据完成")
+}
+
+// Run 按 retention 配置归档 now 之前超过保留天数的数据：先写入按月划分的归档文件，
+// 写入成功后再从数据库删除；使用记录在确认已计入使用汇总后才会删除
+func Run(now time.Time) (*Result, error) {
+	cfg := config.Get().Retention
+	result := &Result{}
+	now = now.In(model.Location())
+
+	usageBefore := cutoff(now, cfg.UsageDays)
+	if err := checkRollups(usageBefore); err != nil {
+		return result, err
+	}
+	files, count, err := archiveTable(cfg.ArchiveDir, "usages", usageBefore, model.ExpiredUsages,
+		func(u *model.Usage) (int64, int64) { return u.Id, u.StartTime },
+		model.DeleteExpiredUsages)
+	result.Files = append(result.Files, files...)
+	result.Usages = count
+	if err != nil {
+		return result, fmt.Errorf("归档使用记录失败: %w", err)
+	}
+
+	files, count, err = archiveTable(cfg.ArchiveDir, "queue_entries", cutoff(now, cfg.QueueDays), model.ExpiredQueueEntries,
+		func(e *model.QueueEntry) (int64, int64) { return e.Id, e.CreatedAt },
+		model.DeleteExpiredQueueEntries)
+	result.Files = append(result.Files, files...)
+	result.QueueEntries = count
+	if err != nil {
+		return result, fmt.Errorf("归档排队记录失败: %w", err)
+	}
+	return result, nil
+}
+
+// cutoff 保留期的起点，取整到 now 所在时区当天零点，保证按天汇总对应的使用记录整天归档
+func cutoff(now time.Time, days int) int64 {
+	t := now.AddDate(0, 0, -days)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Unix()
+}
+
+// checkRollups 确认待归档的使用记录都已计入使用汇总，不一致时重建后再次确认
+func checkRollups(before int64) error {
+	days, err := model.VerifyUsageRollups(before)
+	if err != nil || len(days) == 0 {
+		return err
+	}
+	log.WithField("days", strings.Join(days, ",")).Warn("待归档的使用记录与使用汇总不一致，重建汇总")
+	since, err := time.ParseInLocation(model.DayLayout, days[0], model.Location())
+	if err != nil {
+		return err
+	}
+	if err := model.RebuildUsageRollups(since.Unix(), before); err != nil {
+		return fmt.Errorf("重建使用汇总失败: %w", err)
+	}
+	if days, err = model.VerifyUsageRollups(before); err != nil {
+		return err
+	}
+	if len(days) > 0 {
+		return fmt.Errorf("使用汇总与使用记录不一致，暂不归档: %s", strings.Join(days, ","))
+	}
+	return nil
+}
+
+// archiveTable 将 before 之前过期的记录写入归档文件，全部写入成功后删除已归档的记录
+// key 返回记录的主键和用于划分月份的时间
+func archiveTable[T any](
+	dir, table string,
+	before int64,
+	each func(before int64, fn func(rows []T) error) error,
+	key func(row *T) (id, ts int64),
+	remove func(before, maxId int64) (int64, error),
+) ([]string, int64, error) {
+	w := newMonthlyWriter(dir, table)
+	var maxId int64
+	err := each(before, func(rows []T) error {
+		for i := range rows {
+			id, ts := key(&rows[i])
+			maxId = max(maxId, id)
+			if err := w.write(ts, &rows[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	files, closeErr := w.close()
+	if err != nil {
+		return files, 0, err
+	}
+	if closeErr != nil {
+		return files, 0, closeErr
+	}
+	if maxId == 0 {
+		return files, 0, nil
+	}
+	count, err := remove(before, maxId)
+	return files, count, err
+}
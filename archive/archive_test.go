@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"washwise/config"
+	"washwise/model"
+)
+
+func setupTest(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := model.InitDB(model.DriverSQLite, filepath.Join(dir, "washwise.db")); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Retention.UsageDays = 30
+	cfg.Retention.QueueDays = 7
+	cfg.Retention.ArchiveDir = filepath.Join(dir, "archive")
+	config.Set(cfg)
+	return cfg.Retention.ArchiveDir
+}
+
+// readArchive 读取归档文件中的所有记录
+func readArchive(t *testing.T, path string) []model.DumpRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []model.DumpRecord
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var record model.DumpRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestRun(t *testing.T) {
+	dir := setupTest(t)
+	now := time.Date(2025, 5, 20, 15, 0, 0, 0, time.Local)
+	day := func(y int, m time.Month, d int) int64 {
+		return time.Date(y, m, d, 10, 0, 0, 0, time.Local).Unix()
+	}
+
+	for _, usage := range []model.Usage{
+		{MachineId: 1, StartTime: day(2025, 3, 30), EndTime: day(2025, 3, 30) + 2400},
+		{MachineId: 1, StartTime: day(2025, 4, 1), EndTime: day(2025, 4, 1) + 2400},
+		{MachineId: 2, StartTime: day(2025, 4, 19), EndTime: day(2025, 4, 19) + 2400},
+		{MachineId: 1, StartTime: day(2025, 4, 20), EndTime: day(2025, 4, 20) + 2400}, // 保留期内
+	} {
+		if err := model.CreateUsage(&usage); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, entry := range []model.QueueEntry{
+		{ShopId: "shop", DeviceId: "a", Status: model.QueueStatusClaimed, CreatedAt: day(2025, 5, 1), UpdatedAt: day(2025, 5, 1)},
+		{ShopId: "shop", DeviceId: "b", Status: model.QueueStatusWaiting, CreatedAt: day(2025, 5, 1), UpdatedAt: day(2025, 5, 1)},
+		{ShopId: "shop", DeviceId: "c", Status: model.QueueStatusExpired, CreatedAt: day(2025, 5, 18), UpdatedAt: day(2025, 5, 18)},
+	} {
+		if err := model.CreateQueueEntry(&entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := Run(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Usages != 3 || result.QueueEntries != 1 {
+		t.Fatalf("expected 3 usages and 1 queue entry archived, got %+v", result)
+	}
+	if len(result.Files) != 3 {
+		t.Fatalf("expected 3 archive files, got %v", result.Files)
+	}
+	if records := readArchive(t, filepath.Join(dir, "usages-2025-04.jsonl.gz")); len(records) != 2 || records[0].Table != "usages" {
+		t.Errorf("unexpected records in april archive: %+v", records)
+	}
+
+	// 归档后汇总保留，原始记录删除
+	rows, err := model.GetDailyUsages(1, "2025-03-30", "2025-04-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("expected rollups to be kept, got %+v", rows)
+	}
+	if count, _ := model.CountUsagesSince(0); count != 1 {
+		t.Errorf("expected 1 usage left, got %d", count)
+	}
+
+	// 同一月份的后续归档追加到已有文件；未计入汇总的记录先重建汇总再归档
+	row, _ := json.Marshal(&model.Usage{Id: 100, MachineId: 2, StartTime: day(2025, 4, 25), EndTime: day(2025, 4, 25) + 600})
+	if _, err := model.Restore(&model.DumpRecord{Table: "usages", Row: row}); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Run(now.AddDate(0, 0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Usages != 2 || result.QueueEntries != 1 {
+		t.Fatalf("expected 2 usages and 1 queue entry archived, got %+v", result)
+	}
+	if records := readArchive(t, filepath.Join(dir, "usages-2025-04.jsonl.gz")); len(records) != 4 {
+		t.Errorf("expected 4 records after append, got %d", len(records))
+	}
+	if rows, err := model.GetDailyUsages(2, "2025-04-25", "2025-04-25"); err != nil || len(rows) != 1 || rows[0].UsageCount != 1 {
+		t.Errorf("expected rebuilt rollup for restored usage, got %+v %v", rows, err)
+	}
+}
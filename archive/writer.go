@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+	"washwise/model"
+)
+
+// monthLayout 归档文件名中的月份格式
+const monthLayout = "2006-01"
+
+// monthlyWriter 将一张表的数据按月份追加写入 gzip 压缩的 JSON Lines 文件，
+// 每行与 export 命令的格式相同，可直接用 import 命令导入
+// 每次写入在文件末尾追加一个新的 gzip 成员，读取时会自动连接
+type monthlyWriter struct {
+	dir   string
+	table string
+	files map[string]*monthlyFile
+}
+
+type monthlyFile struct {
+	f   *os.File
+	gz  *gzip.Writer
+	enc *json.Encoder
+}
+
+func newMonthlyWriter(dir, table string) *monthlyWriter {
+	return &monthlyWriter{dir: dir, table: table, files: make(map[string]*monthlyFile)}
+}
+
+// path 指定月份的归档文件路径
+func (w *monthlyWriter) path(month string) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s-%s.jsonl.gz", w.table, month))
+}
+
+// write 将一行写入 ts 在洗衣房时区所在月份的归档文件
+func (w *monthlyWriter) write(ts int64, row any) error {
+	month := time.Unix(ts, 0).In(model.Location()).Format(monthLayout)
+	file, ok := w.files[month]
+	if !ok {
+		if err := os.MkdirAll(w.dir, 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(w.path(month), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		gz := gzip.NewWriter(f)
+		file = &monthlyFile{f: f, gz: gz, enc: json.NewEncoder(gz)}
+		w.files[month] = file
+	}
+
+	raw, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	return file.enc.Encode(&model.DumpRecord{Table: w.table, Row: raw})
+}
+
+// close 写完并同步所有归档文件，返回写入的文件路径
+func (w *monthlyWriter) close() ([]string, error) {
+	var paths []string
+	var errs []error
+	for month, file := range w.files {
+		errs = append(errs, file.gz.Close(), file.f.Sync(), file.f.Close())
+		paths = append(paths, w.path(month))
+	}
+	slices.Sort(paths)
+	return paths, errors.Join(errs...)
+}
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var errBuildPanic = errors.New("生成缓存内容时发生 panic")
+
+// Entry 缓存项，保存生成响应所需的数据而不是响应体，剩余时间等随时间变化的字段在每次请求时计算
+type Entry struct {
+	Value    any
+	ExpireAt time.Time
+}
+
+// call 正在生成的缓存项，相同 key 的并发请求等待同一次生成
+type call struct {
+	done  chan struct{}
+	entry *Entry
+	err   error
+}
+
+// Cache 按商店分组的数据缓存，数据更新时按商店失效
+type Cache struct {
+	mu          sync.Mutex
+	entries     map[string]map[string]*Entry // shopId -> key -> entry
+	generations map[string]uint64            // 每个商店的失效次数，用于丢弃失效前开始生成的结果
+	epoch       uint64                       // 全部失效的次数
+	calls       map[string]*call
+	now         func() time.Time
+	nextSweep   time.Time // 下次清理过期缓存项的时间
+}
+
+// New 创建数据缓存
+func New() *Cache {
+	return &Cache{
+		entries:     make(map[string]map[string]*Entry),
+		generations: make(map[string]uint64),
+		calls:       make(map[string]*call),
+		now:         time.Now,
+	}
+}
+
+// Load 获取缓存项，不存在或已过期时调用 build 生成数据并缓存 ttl，ttl 不大于0时不缓存
+// 相同 key 的并发请求只生成一次；生成期间商店被失效时，结果只返回给本次请求，不写入缓存
+// 缓存的数据在请求间共享，调用方不应修改
+func (c *Cache) Load(shopId, key string, ttl time.Duration, build func() (any, error)) (*Entry, error) {
+	if ttl <= 0 {
+		value, err := build()
+		if err != nil {
+			return nil, err
+		}
+		return &Entry{Value: value, ExpireAt: c.now()}, nil
+	}
+
+	c.mu.Lock()
+	c.sweep(ttl)
+	if entry, ok := c.entries[shopId][key]; ok && c.now().Before(entry.ExpireAt) {
+		c.mu.Unlock()
+		return entry, nil
+	}
+	callKey := shopId + "\x00" + key
+	if cl, ok := c.calls[callKey]; ok {
+		c.mu.Unlock()
+		<-cl.done
+		return cl.entry, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.calls[callKey] = cl
+	generation, epoch := c.generations[shopId], c.epoch
+	c.mu.Unlock()
+
+	// build panic 时也要唤醒等待者
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, callKey)
+		if cl.err == nil && cl.entry != nil && c.generations[shopId] == generation && c.epoch == epoch {
+			if c.entries[shopId] == nil {
+				c.entries[shopId] = make(map[string]*Entry)
+			}
+			c.entries[shopId][key] = cl.entry
+		} else if cl.entry == nil && cl.err == nil {
+			cl.err = errBuildPanic
+		}
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+
+	value, err := build()
+	if err != nil {
+		cl.err = err
+		return nil, err
+	}
+	cl.entry = &Entry{Value: value, ExpireAt: c.now().Add(ttl)}
+	return cl.entry, nil
+}
+
+// sweep 每隔 ttl 删除一次过期的缓存项，不再请求的 key（如按日期生成的 key）不会一直占用内存
+// 调用方需持有锁
+func (c *Cache) sweep(ttl time.Duration) {
+	now := c.now()
+	if now.Before(c.nextSweep) {
+		return
+	}
+	c.nextSweep = now.Add(ttl)
+	for shopId, entries := range c.entries {
+		for key, entry := range entries {
+			if !now.Before(entry.ExpireAt) {
+				delete(entries, key)
+			}
+		}
+		if len(entries) == 0 {
+			delete(c.entries, shopId)
+		}
+	}
+}
+
+// Invalidate 使指定商店的缓存失效
+func (c *Cache) Invalidate(shopIds ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, shopId := range shopIds {
+		delete(c.entries, shopId)
+		c.generations[shopId]++
+	}
+}
+
+// InvalidateAll 使所有缓存失效
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	c.entries = make(map[string]map[string]*Entry)
+}
+
+var defaultCache = New()
+
+// Load 从默认缓存获取数据
+func Load[T any](shopId, key string, ttl time.Duration, build func() (T, error)) (T, error) {
+	entry, err := defaultCache.Load(shopId, key, ttl, func() (any, error) { return build() })
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return entry.Value.(T), nil
+}
+
+// Invalidate 使默认缓存中指定商店的缓存失效
+func Invalidate(shopIds ...string) {
+	defaultCache.Invalidate(shopIds...)
+}
+
+// InvalidateAll 使默认缓存全部失效
+func InvalidateAll() {
+	defaultCache.InvalidateAll()
+}
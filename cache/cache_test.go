@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	c := New()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	builds := 0
+	build := func() (any, error) {
+		builds++
+		return `{"n":1}`, nil
+	}
+	load := func() *Entry {
+		t.Helper()
+		entry, err := c.Load("shop", "machines", 30*time.Second, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+
+	first := load()
+	if second := load(); second != first || builds != 1 {
+		t.Fatalf("expected cached entry, got %d builds", builds)
+	}
+
+	// 其他商店失效不影响
+	c.Invalidate("other")
+	load()
+	if builds != 1 {
+		t.Errorf("expected no rebuild after invalidating another shop, got %d builds", builds)
+	}
+
+	c.Invalidate("shop")
+	load()
+	if builds != 2 {
+		t.Errorf("expected rebuild after invalidation, got %d builds", builds)
+	}
+
+	now = now.Add(31 * time.Second)
+	load()
+	if builds != 3 {
+		t.Errorf("expected rebuild after expiry, got %d builds", builds)
+	}
+
+	// 生成失败不缓存
+	if _, err := c.Load("shop", "failed", time.Minute, func() (any, error) { return nil, errors.New("db") }); err == nil {
+		t.Error("expected build error")
+	}
+	if _, err := c.Load("shop", "failed", time.Minute, build); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadSweepsExpired(t *testing.T) {
+	c := New()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	build := func() (any, error) { return "v", nil }
+	for _, key := range []string{"2024-02-24", "2024-02-25"} {
+		if _, err := c.Load("shop", key, 30*time.Second, build); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Load("other", "machines", 30*time.Second, build); err != nil {
+		t.Fatal(err)
+	}
+
+	// 过期后不再请求的 key 在下次加载时删除
+	now = now.Add(31 * time.Second)
+	if _, err := c.Load("shop", "2024-02-26", 30*time.Second, build); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.entries) != 1 || len(c.entries["shop"]) != 1 || c.entries["shop"]["2024-02-26"] == nil {
+		t.Errorf("expected only the new entry to remain, got %v", c.entries)
+	}
+}
+
+func TestLoadInvalidatedDuringBuild(t *testing.T) {
+	c := New()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = c.Load("shop", "machines", time.Minute, func() (any, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+	}()
+	<-started
+	c.Invalidate("shop")
+	close(release)
+	wg.Wait()
+
+	// 失效前开始生成的结果不写入缓存
+	entry, err := c.Load("shop", "machines", time.Minute, func() (any, error) { return "fresh", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Value != "fresh" {
+		t.Errorf("expected fresh body, got %v", entry.Value)
+	}
+}
+
+func TestETag(t *testing.T) {
+	etag := ETag([]byte(`{"n":1}`))
+	if etag == "" || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		t.Errorf("expected quoted ETag, got %q", etag)
+	}
+	if ETag([]byte(`{"n":1}`)) != etag || ETag([]byte(`{"n":2}`)) == etag {
+		t.Error("expected ETag to depend only on body")
+	}
+}
+
+func TestLoadTyped(t *testing.T) {
+	InvalidateAll()
+	builds := 0
+	load := func() []int {
+		t.Helper()
+		v, err := Load("shop", "typed", time.Minute, func() ([]int, error) {
+			builds++
+			return []int{1, 2}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	if v := load(); len(v) != 2 || len(load()) != 2 || builds != 1 {
+		t.Errorf("expected cached typed value, got %v after %d builds", v, builds)
+	}
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ETag 根据响应体生成强校验 ETag，带引号
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Send 发送 JSON 响应，带 ETag 和 Cache-Control 头
+// 请求的 If-None-Match 与 ETag 一致时返回 304，不发送响应体
+// maxAge 为客户端可直接使用响应的时长，不大于0时要求客户端每次用 ETag 重新验证
+func Send(c *fiber.Ctx, body []byte, maxAge time.Duration) error {
+	c.Set(fiber.HeaderETag, ETag(body))
+	if maxAge > 0 {
+		c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	if c.Fresh() {
+		c.Status(fiber.StatusNotModified)
+		return nil
+	}
+	return c.Send(body)
+}
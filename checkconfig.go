@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"washwise/config"
+	"washwise/model"
+)
+
+// runCheckConfig 加载并校验配置（含环境变量覆盖），打印最终生效的主要配置项
+func runCheckConfig(configPath string, args []string) error {
+	fs := newFlagSet("check-config", &configPath)
+	_ = fs.Parse(args)
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(configPath); err != nil {
+		fmt.Printf("配置文件: %s（不存在，使用默认值和环境变量）\n", configPath)
+	} else {
+		fmt.Printf("配置文件: %s\n", configPath)
+	}
+	fmt.Printf("  日志: level=%s dir=%s format=%s stdout=%t\n", cfg.Log.Level, cfg.Log.Dir, cfg.Log.Format, cfg.Log.Stdout)
+	fmt.Printf("  日志文件: split_levels=%t period=%s max_size=%dMB compress=%t max_age=%s max_files=%d\n",
+		cfg.Log.SplitLevels, cfg.Log.Rotate.Period, cfg.Log.Rotate.MaxSize, cfg.Log.Rotate.Compress, cfg.Log.Rotate.MaxAge, cfg.Log.Rotate.MaxFiles)
+	if cfg.Database.Driver == "sqlite" {
+		fmt.Printf("  数据库: sqlite %s\n", cfg.Database.Path)
+	} else {
+		fmt.Printf("  数据库: %s（连接串已隐藏）\n", cfg.Database.Driver)
+	}
+	if !slices.Contains(model.Drivers(), cfg.Database.Driver) {
+		return fmt.Errorf("数据库驱动 %s 未编译进程序，可用: %s", cfg.Database.Driver, strings.Join(model.Drivers(), ", "))
+	}
+	security := cfg.Server.Security
+	listen := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	if cfg.Server.Socket != "" {
+		listen = "unix:" + cfg.Server.Socket
+	}
+	fmt.Printf("  HTTP: %s tls=%t 关闭等待=%s 可信代理=%v 代理请求头=%s\n", listen, cfg.Server.TLS.CertFile != "",
+		cfg.Server.ShutdownTimeout, security.TrustedProxies, security.ProxyHeader)
+	if cfg.Server.TLS.CertFile != "" {
+		if _, err := tls.LoadX509KeyPair(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile); err != nil {
+			return fmt.Errorf("加载 TLS 证书失败: %w", err)
+		}
+	}
+	fmt.Printf("  跨域: 来源=%v 方法=%v 凭证=%t\n", security.AllowedOrigins, security.AllowedMethods, security.AllowCredentials)
+	fmt.Printf("  限流: enabled=%t 窗口=%s 读=%d/IP %d/设备 写=%d/IP %d/设备\n", cfg.RateLimit.Enabled, cfg.RateLimit.Window,
+		cfg.RateLimit.Read.IP, cfg.RateLimit.Read.Device, cfg.RateLimit.Write.IP, cfg.RateLimit.Write.Device)
+	fmt.Printf("  定时任务: enabled=%t 类型=%s 列表=%s 详情=%s 关闭等待=%s\n", cfg.Cron.Enabled,
+		config.GetMachineTypesInterval(), config.GetMachinesInterval(), config.GetMachineDetailsInterval(), cfg.Cron.ShutdownTimeout)
+	fmt.Printf("  排队: 宽限期=%s 最长等待=%s 检查周期=%s webhook 主机=%v\n",
+		config.GetQueueGracePeriod(), config.GetQueueMaxWait(), config.GetQueueCheckInterval(), cfg.Queue.WebhookHosts)
+	fmt.Printf("  响应缓存: enabled=%t 缓存时长=%s max_age=%s\n", cfg.Cache.Enabled, config.GetCacheTTL(), cfg.Cache.MaxAge)
+	fmt.Printf("  数据保留: enabled=%t 使用记录=%d天 排队记录=%d天 归档目录=%s 周期=%s\n", cfg.Retention.Enabled,
+		cfg.Retention.UsageDays, cfg.Retention.QueueDays, cfg.Retention.ArchiveDir, config.GetRetentionInterval())
+	fmt.Printf("  商店: %d 个\n", len(cfg.Shops))
+	for _, shopId := range cfg.Shops {
+		fmt.Printf("    - %s 平台=%s\n", shopId, cfg.ShopVendor(shopId))
+	}
+	fmt.Println("配置有效")
+	return nil
+}
@@ -1,70 +1,538 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
+	_ "time/tzdata" // 内置时区数据，不依赖运行环境的 tzdata
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Log struct {
-		Level string `yaml:"level"`
-		Dir   string `yaml:"dir"`
+		Level  string `yaml:"level"`
+		Dir    string `yaml:"dir"`
+		Format string `yaml:"format"` // text 或 json
+		Stdout bool   `yaml:"stdout"` // 是否同时输出到标准输出
+
+		SplitLevels bool `yaml:"split_levels"` // 是否按级别写入不同文件，否则写入同一个文件
+		Rotate      struct {
+			Period   string   `yaml:"period"`    // 轮换周期，hour 或 day
+			MaxSize  int      `yaml:"max_size"`  // 单个文件最大大小，单位 MB，0 表示不限
+			Compress bool     `yaml:"compress"`  // 是否 gzip 压缩轮换后的文件
+			MaxAge   Duration `yaml:"max_age"`   // 轮换后的文件保留时长，0 表示不限
+			MaxFiles int      `yaml:"max_files"` // 每类日志轮换后的文件最多保留个数，0 表示不限
+		} `yaml:"rotate"`
 	} `yaml:"log"`
 
 	Database struct {
-		Path string `yaml:"path"`
+		Driver string `yaml:"driver"` // sqlite、postgres 或 mysql
+		Path   string `yaml:"path"`   // SQLite 数据库文件路径
+		DSN    string `yaml:"dsn"`    // PostgreSQL/MySQL 连接串
 	} `yaml:"database"`
 
 	Shops []string `yaml:"shops"`
+	// ShopVendors 商店使用的洗衣平台，商店ID -> 平台名称（qiekj 或 vendors 中的名称），未配置的商店使用 qiekj
+	ShopVendors map[string]string `yaml:"shop_vendors"`
+	// Vendors 自定义洗衣平台，名称 -> 平台配置
+	Vendors map[string]Vendor `yaml:"vendors"`
+
+	// Timezone 洗衣房所在地的 IANA 时区，按天统计和接口返回的时间都使用该时区
+	Timezone string `yaml:"timezone"`
+	location *time.Location
 
 	Server struct {
 		Host string `yaml:"host"`
 		Port int    `yaml:"port"`
+		// Socket unix socket 路径，设置后监听该 socket 而不是 host:port，用于同机反向代理
+		Socket          string   `yaml:"socket"`
+		ShutdownTimeout Duration `yaml:"shutdown_timeout"` // 关闭时等待处理中请求完成的最长时间
+		TLS             struct {
+			CertFile string `yaml:"cert_file"` // 证书文件（PEM，可包含中间证书），文件更新后自动重新加载
+			KeyFile  string `yaml:"key_file"`  // 私钥文件（PEM）
+		} `yaml:"tls"`
+		Security struct {
+			AllowedOrigins   []string `yaml:"allowed_origins"`   // 允许跨域访问的来源，* 表示任意来源
+			AllowedMethods   []string `yaml:"allowed_methods"`   // 允许跨域使用的请求方法
+			AllowCredentials bool     `yaml:"allow_credentials"` // 是否允许跨域请求携带 Cookie 等凭证，不能与 * 来源同时使用
+			// TrustedProxies 可信反向代理的 IP 或 CIDR，来自这些地址的请求按 ProxyHeader 确定客户端 IP
+			TrustedProxies []string `yaml:"trusted_proxies"`
+			ProxyHeader    string   `yaml:"proxy_header"` // 代理传递客户端 IP 的请求头，如 X-Forwarded-For、X-Real-IP
+		} `yaml:"security"`
 	} `yaml:"server"`
 
 	Cron struct {
-		Enabled                bool `yaml:"enabled"`
-		MachineTypesInterval   int  `yaml:"machine_types_interval"`
-		MachinesInterval       int  `yaml:"machines_interval"`
-		MachineDetailsInterval int  `yaml:"machine_details_interval"`
+		Enabled                bool     `yaml:"enabled"`
+		MachineTypesInterval   Duration `yaml:"machine_types_interval"`
+		MachinesInterval       Duration `yaml:"machines_interval"`
+		MachineDetailsInterval Duration `yaml:"machine_details_interval"`
+		ShutdownTimeout        Duration `yaml:"shutdown_timeout"` // 关闭时等待进行中的任务、写库和排队 webhook 通知完成的最长时间
 	} `yaml:"cron"`
+
+	Queue struct {
+		GracePeriod   Duration `yaml:"grace_period"`
+		MaxWait       Duration `yaml:"max_wait"`
+		CheckInterval Duration `yaml:"check_interval"`
+		// WebhookHosts 允许推送 webhook 的主机，支持 *.example.com，为空时允许任意公网主机
+		WebhookHosts []string `yaml:"webhook_hosts"`
+	} `yaml:"queue"`
+
+	Admin struct {
+		Tokens []string `yaml:"tokens"` // 管理员令牌，请求时通过 Authorization: Bearer <token> 携带
+	} `yaml:"admin"`
+
+	RateLimit struct {
+		Enabled bool       `yaml:"enabled"`
+		Window  Duration   `yaml:"window"` // 限流窗口，预算为每个窗口内的请求数
+		Read    RateBudget `yaml:"read"`   // 读接口预算
+		Write   RateBudget `yaml:"write"`  // 写接口（非 GET 请求及点赞、点踩）预算
+	} `yaml:"rate_limit"`
+
+	Cache struct {
+		Enabled bool     `yaml:"enabled"` // 是否缓存机器列表接口查询的机器数据，缓存在机器详情更新后失效
+		MaxAge  Duration `yaml:"max_age"` // 响应 Cache-Control 的 max-age，0 表示客户端每次都用 ETag 重新验证
+	} `yaml:"cache"`
+
+	Export struct {
+		Public bool `yaml:"public"` // 是否允许不带管理员令牌导出使用记录
+	} `yaml:"export"`
+
+	Retention struct {
+		Enabled    bool     `yaml:"enabled"`
+		UsageDays  int      `yaml:"usage_days"`  // 使用记录保留天数
+		QueueDays  int      `yaml:"queue_days"`  // 已结束排队记录保留天数
+		ArchiveDir string   `yaml:"archive_dir"` // 归档文件目录
+		Interval   Duration `yaml:"interval"`    // 执行归档的周期
+	} `yaml:"retention"`
+}
+
+// Vendor 洗衣平台配置，目前只支持 http_json：通过 HTTP GET 获取 JSON 数据，按字段映射解析
+type Vendor struct {
+	Type    string            `yaml:"type"`     // 平台类型，目前只支持 http_json
+	BaseURL string            `yaml:"base_url"` // 接口地址前缀
+	Headers map[string]string `yaml:"headers"`  // 请求头，如鉴权信息
+	Timeout Duration          `yaml:"timeout"`  // 单个请求超时，0 表示默认 10s
+
+	MachinesPath  string `yaml:"machines_path"`  // 机器列表接口路径，{shopId} 替换为商店ID
+	MachinesItems string `yaml:"machines_items"` // 响应中机器数组的字段路径（以 . 分隔），空表示响应本身是数组
+	StatusPath    string `yaml:"status_path"`    // 单台机器状态接口路径，{shopId}、{machineId} 替换为商店ID和机器ID
+	StatusData    string `yaml:"status_data"`    // 响应中机器对象的字段路径，空表示响应本身
+
+	// Fields 机器对象中各字段的路径，未设置时使用同名字段
+	Fields struct {
+		Id     string `yaml:"id"`     // 机器ID，需为整数或整数字符串
+		Name   string `yaml:"name"`   // 机器名称
+		Type   string `yaml:"type"`   // 机器类型名称，如“洗衣机”
+		Status string `yaml:"status"` // 平台状态码
+		Msg    string `yaml:"msg"`    // 状态说明，可选
+	} `yaml:"fields"`
+
+	// StatusMap 平台状态码 -> 统一状态（available、in_use 或 offline），未列出的状态视为 offline
+	StatusMap map[string]string `yaml:"status_map"`
+}
+
+// 洗衣平台类型
+const (
+	VendorQiekj    = "qiekj"
+	VendorHTTPJSON = "http_json"
+)
+
+// 洗衣平台状态码映射使用的统一状态
+const (
+	StatusAvailable = "available"
+	StatusInUse     = "in_use"
+	StatusOffline   = "offline"
+)
+
+var vendorStatuses = []string{StatusAvailable, StatusInUse, StatusOffline}
+
+// RateBudget 限流预算，0 表示不限制
+type RateBudget struct {
+	IP     int `yaml:"ip"`     // 每个客户端 IP 的请求数
+	Device int `yaml:"device"` // 每个设备（X-Device-Id）的请求数，设备标识由客户端自行声明，可随意更换
+}
+
+var cfg atomic.Pointer[Config]
+
+var databaseDrivers = []string{"sqlite", "postgres", "mysql"}
+
+// minAdminTokenLen 管理员令牌的最小长度
+const minAdminTokenLen = 16
+
+var logLevels = []string{"trace", "debug", "info", "warn", "warning", "error", "fatal", "panic"}
+
+var logFormats = []string{"text", "json"}
+
+var logRotatePeriods = []string{"hour", "day"}
+
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// validOrigin 判断跨域来源格式是否合法，支持 https://*.example.com 形式的子域名通配
+func validOrigin(origin string) bool {
+	origin = strings.Replace(origin, "://*.", "://", 1)
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == "" && u.User == nil
 }
 
-var cfg *Config
+// Default 返回默认配置
+func Default() *Config {
+	c := &Config{}
+	c.Log.Level = "info"
+	c.Log.Dir = "logs"
+	c.Log.Format = "text"
+	c.Log.SplitLevels = true
+	c.Log.Rotate.Period = "day"
+	c.Log.Rotate.MaxSize = 100
+	c.Log.Rotate.Compress = true
+	c.Log.Rotate.MaxAge = Duration(30 * 24 * time.Hour)
+	c.Database.Driver = "sqlite"
+	c.Database.Path = "./data/washwise.db"
+	c.Timezone = "Asia/Shanghai"
+	c.Server.Host = "0.0.0.0"
+	c.Server.Port = 8000
+	c.Server.ShutdownTimeout = Duration(10 * time.Second)
+	c.Server.Security.AllowedOrigins = []string{"*"}
+	c.Server.Security.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE"}
+	c.Server.Security.ProxyHeader = "X-Forwarded-For"
+	c.Cron.Enabled = true
+	c.Cron.MachineTypesInterval = Duration(time.Hour)
+	c.Cron.MachinesInterval = Duration(5 * time.Minute)
+	c.Cron.MachineDetailsInterval = Duration(30 * time.Second)
+	c.Cron.ShutdownTimeout = Duration(30 * time.Second)
+	c.Queue.GracePeriod = Duration(3 * time.Minute)
+	c.Queue.MaxWait = Duration(3 * time.Hour)
+	c.Queue.CheckInterval = Duration(10 * time.Second)
+	c.RateLimit.Enabled = true
+	c.RateLimit.Window = Duration(time.Minute)
+	c.RateLimit.Read = RateBudget{IP: 1200, Device: 240}
+	c.RateLimit.Write = RateBudget{IP: 120, Device: 20}
+	c.Cache.Enabled = true
+	c.Cache.MaxAge = Duration(5 * time.Second)
+	c.Retention.UsageDays = 180
+	c.Retention.QueueDays = 30
+	c.Retention.ArchiveDir = "./data/archive"
+	c.Retention.Interval = Duration(24 * time.Hour)
+	return c
+}
 
-// Load 加载配置文件
+// Load 加载配置：依次应用默认值、配置文件和 WASHWISE_* 环境变量，并校验
+// 配置文件不存在时只使用默认值和环境变量
 func Load(path string) error {
-	data, err := os.ReadFile(path)
+	c, err := load(path, os.LookupEnv)
 	if err != nil {
 		return err
 	}
+	reloadMux.Lock()
+	defer reloadMux.Unlock()
+	configPath = path
+	cfg.Store(c)
+	return nil
+}
 
-	cfg = &Config{}
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return err
+func load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %w", err)
+		}
 	}
 
-	return nil
+	if err := applyEnv(c, lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-// Get 获取配置实例
+// Validate 校验配置，返回所有不合法字段的错误
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if !slices.Contains(logLevels, strings.ToLower(c.Log.Level)) {
+		fail("log.level", "未知日志等级 %q，可选 %s", c.Log.Level, strings.Join(logLevels, ", "))
+	}
+	if !slices.Contains(logFormats, c.Log.Format) {
+		fail("log.format", "未知日志格式 %q，可选 %s", c.Log.Format, strings.Join(logFormats, ", "))
+	}
+	if c.Log.Dir == "" {
+		fail("log.dir", "不能为空")
+	}
+	if !slices.Contains(logRotatePeriods, c.Log.Rotate.Period) {
+		fail("log.rotate.period", "未知轮换周期 %q，可选 %s", c.Log.Rotate.Period, strings.Join(logRotatePeriods, ", "))
+	}
+	if c.Log.Rotate.MaxSize < 0 {
+		fail("log.rotate.max_size", "不能为负数")
+	}
+	if c.Log.Rotate.MaxAge < 0 {
+		fail("log.rotate.max_age", "不能为负数")
+	}
+	if c.Log.Rotate.MaxFiles < 0 {
+		fail("log.rotate.max_files", "不能为负数")
+	}
+	if !slices.Contains(databaseDrivers, c.Database.Driver) {
+		fail("database.driver", "未知数据库驱动 %q，可选 %s", c.Database.Driver, strings.Join(databaseDrivers, ", "))
+	} else if c.Database.Driver == "sqlite" && c.Database.Path == "" {
+		fail("database.path", "使用 sqlite 时不能为空")
+	} else if c.Database.Driver != "sqlite" && c.Database.DSN == "" {
+		fail("database.dsn", "使用 %s 时不能为空", c.Database.Driver)
+	}
+
+	if len(c.Shops) == 0 {
+		fail("shops", "至少需要配置一个商店ID")
+	}
+	for i, shopId := range c.Shops {
+		if strings.TrimSpace(shopId) == "" {
+			fail(fmt.Sprintf("shops[%d]", i), "不能为空")
+		} else if slices.Index(c.Shops, shopId) != i {
+			fail(fmt.Sprintf("shops[%d]", i), "商店ID %s 重复", shopId)
+		}
+	}
+
+	for _, shopId := range slices.Sorted(maps.Keys(c.ShopVendors)) {
+		field := "shop_vendors." + shopId
+		name := c.ShopVendors[shopId]
+		if !slices.Contains(c.Shops, shopId) {
+			fail(field, "商店ID %s 不在 shops 中", shopId)
+		} else if _, ok := c.Vendors[name]; !ok && name != VendorQiekj {
+			fail(field, "未知洗衣平台 %q，应为 %s 或 vendors 中的名称", name, VendorQiekj)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Vendors)) {
+		validateVendor("vendors."+name, name, c.Vendors[name], fail)
+	}
+
+	if loc, err := time.LoadLocation(c.Timezone); err != nil || c.Timezone == "" {
+		fail("timezone", "未知时区 %q，应为 IANA 时区名称，如 Asia/Shanghai", c.Timezone)
+	} else {
+		c.location = loc
+	}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		fail("server.port", "端口 %d 超出范围 1-65535", c.Server.Port)
+	}
+
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		fail("server.tls", "cert_file 和 key_file 需要同时设置")
+	}
+
+	security := &c.Server.Security
+	if len(security.AllowedOrigins) == 0 {
+		fail("server.security.allowed_origins", "至少需要一个来源，允许任意来源时使用 *")
+	}
+	for i, origin := range security.AllowedOrigins {
+		if origin == "*" {
+			if security.AllowCredentials {
+				fail(fmt.Sprintf("server.security.allowed_origins[%d]", i), "allow_credentials 开启时不能使用 *")
+			}
+		} else if !validOrigin(origin) {
+			fail(fmt.Sprintf("server.security.allowed_origins[%d]", i), "来源 %q 应为 scheme://host[:port]，如 https://laundry.example.com", origin)
+		}
+	}
+	for i, method := range security.AllowedMethods {
+		if !slices.Contains(httpMethods, method) {
+			fail(fmt.Sprintf("server.security.allowed_methods[%d]", i), "未知请求方法 %q，可选 %s", method, strings.Join(httpMethods, ", "))
+		}
+	}
+	if strings.TrimSpace(security.ProxyHeader) == "" {
+		fail("server.security.proxy_header", "不能为空")
+	}
+	for i, proxy := range c.Server.Security.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				fail(fmt.Sprintf("server.security.trusted_proxies[%d]", i), "无法解析 IP 或 CIDR %q", proxy)
+			}
+		}
+	}
+
+	positive := map[string]Duration{
+		"cron.machine_types_interval":   c.Cron.MachineTypesInterval,
+		"cron.machines_interval":        c.Cron.MachinesInterval,
+		"cron.machine_details_interval": c.Cron.MachineDetailsInterval,
+		"cron.shutdown_timeout":         c.Cron.ShutdownTimeout,
+		"queue.grace_period":            c.Queue.GracePeriod,
+		"queue.check_interval":          c.Queue.CheckInterval,
+		"retention.interval":            c.Retention.Interval,
+		"rate_limit.window":             c.RateLimit.Window,
+		"server.shutdown_timeout":       c.Server.ShutdownTimeout,
+	}
+	for _, field := range slices.Sorted(maps.Keys(positive)) {
+		if positive[field] <= 0 {
+			fail(field, "必须大于0，当前为 %s", positive[field])
+		}
+	}
+	budgets := map[string]int{
+		"rate_limit.read.ip":      c.RateLimit.Read.IP,
+		"rate_limit.read.device":  c.RateLimit.Read.Device,
+		"rate_limit.write.ip":     c.RateLimit.Write.IP,
+		"rate_limit.write.device": c.RateLimit.Write.Device,
+	}
+	for _, field := range slices.Sorted(maps.Keys(budgets)) {
+		if budgets[field] < 0 {
+			fail(field, "不能为负数")
+		}
+	}
+	if c.Cache.MaxAge < 0 {
+		fail("cache.max_age", "不能为负数")
+	}
+	if c.Queue.MaxWait < 0 {
+		fail("queue.max_wait", "不能为负数")
+	}
+
+	for i, host := range c.Queue.WebhookHosts {
+		if strings.TrimPrefix(host, "*.") == "" || strings.ContainsAny(host, "/:@ ") {
+			fail(fmt.Sprintf("queue.webhook_hosts[%d]", i), "主机 %q 应为域名或 *.example.com 形式", host)
+		}
+	}
+
+	for i, token := range c.Admin.Tokens {
+		if len(token) < minAdminTokenLen {
+			fail(fmt.Sprintf("admin.tokens[%d]", i), "长度至少 %d 个字符", minAdminTokenLen)
+		}
+	}
+
+	if c.Retention.UsageDays <= 0 {
+		fail("retention.usage_days", "必须大于0，当前为 %d", c.Retention.UsageDays)
+	}
+	if c.Retention.QueueDays <= 0 {
+		fail("retention.queue_days", "必须大于0，当前为 %d", c.Retention.QueueDays)
+	}
+	if c.Retention.ArchiveDir == "" {
+		fail("retention.archive_dir", "不能为空")
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateVendor 校验自定义洗衣平台配置
+func validateVendor(field, name string, v Vendor, fail func(field, format string, args ...any)) {
+	if name == VendorQiekj {
+		fail(field, "%s 为内置平台，不能重新定义", VendorQiekj)
+		return
+	}
+	if v.Type != VendorHTTPJSON {
+		fail(field+".type", "未知平台类型 %q，可选 %s", v.Type, VendorHTTPJSON)
+		return
+	}
+	if u, err := url.Parse(v.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail(field+".base_url", "接口地址 %q 应为 http:// 或 https:// 开头的 URL", v.BaseURL)
+	}
+	if v.Timeout < 0 {
+		fail(field+".timeout", "不能为负数")
+	}
+	if !strings.Contains(v.MachinesPath, "{shopId}") {
+		fail(field+".machines_path", "需要包含 {shopId}")
+	}
+	if !strings.Contains(v.StatusPath, "{machineId}") {
+		fail(field+".status_path", "需要包含 {machineId}")
+	}
+	if len(v.StatusMap) == 0 {
+		fail(field+".status_map", "至少需要一个状态映射")
+	}
+	for _, code := range slices.Sorted(maps.Keys(v.StatusMap)) {
+		if status := v.StatusMap[code]; !slices.Contains(vendorStatuses, status) {
+			fail(field+".status_map."+code, "未知状态 %q，可选 %s", status, strings.Join(vendorStatuses, ", "))
+		}
+	}
+}
+
+// ShopVendor 获取商店使用的洗衣平台名称
+func (c *Config) ShopVendor(shopId string) string {
+	if name, ok := c.ShopVendors[shopId]; ok {
+		return name
+	}
+	return VendorQiekj
+}
+
+// Set 直接替换当前配置，不触发变更回调，用于测试
+func Set(c *Config) {
+	cfg.Store(c)
+}
+
+// DatabaseDSN 返回数据库连接串，SQLite 为数据库文件路径
+func (c *Config) DatabaseDSN() string {
+	if c.Database.Driver == "sqlite" {
+		return c.Database.Path
+	}
+	return c.Database.DSN
+}
+
+// Location 洗衣房所在地的时区，配置校验通过后可用
+func (c *Config) Location() *time.Location {
+	if c.location == nil {
+		if loc, err := time.LoadLocation(c.Timezone); err == nil {
+			return loc
+		}
+		return time.Local
+	}
+	return c.location
+}
+
+// Get 获取当前配置，热重载时整体替换，调用方不应修改返回值
 func Get() *Config {
-	return cfg
+	return cfg.Load()
 }
 
 // GetMachineTypesInterval 获取机器类型更新间隔
 func GetMachineTypesInterval() time.Duration {
-	return time.Duration(cfg.Cron.MachineTypesInterval) * time.Second
+	return Get().Cron.MachineTypesInterval.Duration()
 }
 
 // GetMachinesInterval 获取机器列表更新间隔
 func GetMachinesInterval() time.Duration {
-	return time.Duration(cfg.Cron.MachinesInterval) * time.Second
+	return Get().Cron.MachinesInterval.Duration()
 }
 
 // GetMachineDetailsInterval 获取机器详情更新间隔
 func GetMachineDetailsInterval() time.Duration {
-	return time.Duration(cfg.Cron.MachineDetailsInterval) * time.Second
+	return Get().Cron.MachineDetailsInterval.Duration()
+}
+
+// GetCacheTTL 获取响应缓存时长，未启用缓存时为0
+func GetCacheTTL() time.Duration {
+	c := Get()
+	if !c.Cache.Enabled {
+		return 0
+	}
+	return c.Cron.MachineDetailsInterval.Duration()
+}
+
+// GetQueueGracePeriod 获取排队通知后的认领宽限期
+func GetQueueGracePeriod() time.Duration {
+	return Get().Queue.GracePeriod.Duration()
+}
+
+// GetQueueMaxWait 获取排队最长等待时间
+func GetQueueMaxWait() time.Duration {
+	return Get().Queue.MaxWait.Duration()
+}
+
+// GetQueueCheckInterval 获取排队状态检查周期
+func GetQueueCheckInterval() time.Duration {
+	return Get().Queue.CheckInterval.Duration()
+}
+
+// GetRetentionInterval 获取数据归档周期
+func GetRetentionInterval() time.Duration {
+	return Get().Retention.Interval.Duration()
 }
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoadDurations(t *testing.T) {
+	path := writeConfig(t, `
+shops: ["a"]
+cron:
+  machine_types_interval: 2h
+  machines_interval: 120
+  machine_details_interval: 1m30s
+`)
+	c, err := load(path, envMap(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		name     string
+		got      Duration
+		expected time.Duration
+	}{
+		{"machine_types_interval", c.Cron.MachineTypesInterval, 2 * time.Hour},
+		{"machines_interval", c.Cron.MachinesInterval, 2 * time.Minute},
+		{"machine_details_interval", c.Cron.MachineDetailsInterval, 90 * time.Second},
+		{"grace_period", c.Queue.GracePeriod, 3 * time.Minute},
+	} {
+		if v.got.Duration() != v.expected {
+			t.Errorf("%s: expected %s, got %s", v.name, v.expected, v.got)
+		}
+	}
+
+	if _, err := load(writeConfig(t, "shops: [a]\ncron:\n  machines_interval: soon\n"), envMap(nil)); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestLoadEnvOverride(t *testing.T) {
+	path := writeConfig(t, `
+shops: ["a"]
+server:
+  port: 8000
+cron:
+  enabled: false
+`)
+	c, err := load(path, envMap(map[string]string{
+		"WASHWISE_SHOPS":                  "b, c",
+		"WASHWISE_SERVER_PORT":            "9000",
+		"WASHWISE_CRON_ENABLED":           "true",
+		"WASHWISE_CRON_MACHINES_INTERVAL": "10m",
+		"WASHWISE_LOG_LEVEL":              "debug",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(c.Shops, ",") != "b,c" {
+		t.Errorf("expected shops b,c, got %v", c.Shops)
+	}
+	if c.Server.Port != 9000 || !c.Cron.Enabled || c.Log.Level != "debug" {
+		t.Errorf("env not applied: port=%d enabled=%t level=%s", c.Server.Port, c.Cron.Enabled, c.Log.Level)
+	}
+	if c.Cron.MachinesInterval.Duration() != 10*time.Minute {
+		t.Errorf("expected machines_interval 10m, got %s", c.Cron.MachinesInterval)
+	}
+
+	if _, err := load(path, envMap(map[string]string{"WASHWISE_SERVER_PORT": "abc"})); err == nil {
+		t.Error("expected error for invalid port")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	c, err := load(filepath.Join(t.TempDir(), "missing.yaml"), envMap(map[string]string{"WASHWISE_SHOPS": "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Server.Port != 8000 || c.Database.Path == "" {
+		t.Errorf("expected defaults, got port=%d db=%q", c.Server.Port, c.Database.Path)
+	}
+	if c.Location().String() != "Asia/Shanghai" {
+		t.Errorf("expected default timezone Asia/Shanghai, got %s", c.Location())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := Default()
+	c.Log.Level = "verbose"
+	c.Shops = []string{"a", "a"}
+	c.Server.Port = 0
+	c.Cron.MachineDetailsInterval = 0
+	c.Timezone = "Mars/Olympus"
+	c.Log.Rotate.Period = "week"
+	c.Log.Rotate.MaxFiles = -1
+	c.Server.Security.TrustedProxies = []string{"10.0.0.0/8", "nginx"}
+	c.Server.Security.AllowedOrigins = []string{"https://*.example.com", "example.com", "*"}
+	c.Server.Security.AllowCredentials = true
+	c.RateLimit.Write.Device = -1
+	c.Server.TLS.CertFile = "cert.pem"
+	c.Queue.WebhookHosts = []string{"*.example.com", "http://example.org"}
+	c.ShopVendors = map[string]string{"a": "campus", "z": "qiekj"}
+	c.Vendors = map[string]Vendor{"campus": {Type: VendorHTTPJSON, BaseURL: "ftp://example.com", MachinesPath: "/machines",
+		StatusPath: "/machines/{machineId}", StatusMap: map[string]string{"1": "busy"}}}
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if strings.Contains(err.Error(), "allowed_origins[0]") {
+		t.Errorf("expected wildcard subdomain origin to be valid, got %q", err)
+	}
+	for _, field := range []string{"log.level", "shops[1]", "server.port", "cron.machine_details_interval", "timezone", "log.rotate.period", "log.rotate.max_files",
+		"server.security.trusted_proxies[1]", "rate_limit.write.device", "server.tls",
+		"shop_vendors.z", "vendors.campus.base_url", "vendors.campus.machines_path", "vendors.campus.status_map.1",
+		"queue.webhook_hosts[1]",
+		"server.security.allowed_origins[1]", "server.security.allowed_origins[2]"} {
+		if !strings.Contains(err.Error(), field+":") {
+			t.Errorf("expected error for %s, got %q", field, err)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, "shops: [a]\ncron:\n  machines_interval: 5m\n")
+	if err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var changed [2]*Config
+	OnChange(func(old, new *Config) {
+		changed = [2]*Config{old, new}
+	})
+
+	if err := os.WriteFile(path, []byte("shops: [a, b]\ncron:\n  machines_interval: 1m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if changed[0] == nil || len(changed[0].Shops) != 1 || len(changed[1].Shops) != 2 {
+		t.Fatalf("expected hook with old and new shops, got %v", changed)
+	}
+	if GetMachinesInterval() != time.Minute {
+		t.Errorf("expected machines_interval 1m, got %s", GetMachinesInterval())
+	}
+
+	// 校验失败时保留当前配置
+	if err := os.WriteFile(path, []byte("shops: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reload(); err == nil {
+		t.Fatal("expected reload error")
+	}
+	if len(Get().Shops) != 2 {
+		t.Errorf("expected config to be kept, got shops %v", Get().Shops)
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration 配置中的时间间隔，支持 "30s"、"5m" 等字符串，纯数字按秒计（兼容旧配置）
+type Duration time.Duration
+
+// Duration 转换为 time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler 接口
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	v, err := parseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("第 %d 行: %w", node.Line, err)
+	}
+	*d = v
+	return nil
+}
+
+// MarshalYAML 实现 yaml.Marshaler 接口
+func (d Duration) MarshalYAML() (any, error) {
+	return d.String(), nil
+}
+
+func parseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Duration(time.Duration(seconds) * time.Second), nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析时间间隔 %q，应为秒数或 30s、5m 等格式", s)
+	}
+	return Duration(v), nil
+}
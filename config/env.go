@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix 环境变量前缀，变量名由 yaml 路径转换而来，如 server.port 对应 WASHWISE_SERVER_PORT，
+// 列表以逗号分隔，如 WASHWISE_SHOPS=id1,id2
+const EnvPrefix = "WASHWISE_"
+
+var durationType = reflect.TypeOf(Duration(0))
+
+// applyEnv 使用环境变量覆盖配置
+func applyEnv(c *Config, lookupEnv func(string) (string, bool)) error {
+	return applyEnvValue(reflect.ValueOf(c).Elem(), EnvPrefix, lookupEnv)
+}
+
+func applyEnvValue(v reflect.Value, prefix string, lookupEnv func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(tag)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvValue(field, name+"_", lookupEnv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(field, value); err != nil {
+			return fmt.Errorf("环境变量 %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setEnvValue(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("无法解析整数 %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("无法解析布尔值 %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("不支持的类型 %s", field.Type())
+		}
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的类型 %s", field.Type())
+	}
+	return nil
+}
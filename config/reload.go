@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	reloadMux  sync.Mutex // 串行化配置加载和变更回调
+	configPath string
+	hooks      []func(old, new *Config)
+)
+
+// OnChange 注册配置变更回调，重新加载成功后按注册顺序调用
+func OnChange(fn func(old, new *Config)) {
+	reloadMux.Lock()
+	defer reloadMux.Unlock()
+	hooks = append(hooks, fn)
+}
+
+// Reload 重新加载配置文件，校验失败时保留当前配置
+func Reload() error {
+	reloadMux.Lock()
+	defer reloadMux.Unlock()
+
+	c, err := load(configPath, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	old := cfg.Swap(c)
+
+	logFiles := old.Log.Dir != c.Log.Dir || old.Log.SplitLevels != c.Log.SplitLevels || old.Log.Rotate != c.Log.Rotate
+	if !reflect.DeepEqual(old.Server, c.Server) || old.Database != c.Database || logFiles || old.Timezone != c.Timezone {
+		log.Warn("server、database、log.dir、log.split_levels、log.rotate、timezone 配置变更需要重启服务才能生效")
+	}
+	for _, fn := range hooks {
+		fn(old, c)
+	}
+	log.WithField("path", configPath).Info("配置已重新加载")
+	return nil
+}
+
+// Watch 定期检查配置文件修改时间，变化时自动重新加载，直到 ctx 结束
+func Watch(ctx context.Context, interval time.Duration) {
+	modTime := func() time.Time {
+		reloadMux.Lock()
+		path := configPath
+		reloadMux.Unlock()
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := modTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := modTime()
+			if current.Equal(last) {
+				continue
+			}
+			last = current
+			if err := Reload(); err != nil {
+				log.WithError(err).Error("配置文件已修改，但重新加载失败，继续使用当前配置")
+			}
+		}
+	}
+}
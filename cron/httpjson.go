@@ -0,0 +1,242 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"washwise/config"
+	"washwise/model"
+)
+
+const (
+	defaultVendorTimeout = 10 * time.Second // 通用洗衣平台的默认请求超时
+	maxVendorResponse    = 8 << 20          // 响应体最大字节数，超出部分不读取，解析失败
+)
+
+// httpJSONVendor 通用洗衣平台，通过 HTTP GET 获取 JSON 数据，字段位置和状态码映射由配置指定
+// 机器类型从机器列表中的类型字段汇总得到
+type httpJSONVendor struct {
+	cfg    config.Vendor
+	client *http.Client
+}
+
+// httpJSONMachine 按字段映射解析出的机器
+type httpJSONMachine struct {
+	Id     int64
+	Name   string
+	Type   string
+	Status string
+	Msg    string
+}
+
+func newHTTPJSONVendor(cfg config.Vendor) *httpJSONVendor {
+	timeout := cfg.Timeout.Duration()
+	if timeout == 0 {
+		timeout = defaultVendorTimeout
+	}
+	return &httpJSONVendor{cfg: cfg, client: &http.Client{Timeout: timeout}}
+}
+
+// get 请求接口并解析 JSON，路径中的占位符替换为转义后的值
+func (v *httpJSONVendor) get(ctx context.Context, path string, shopId string, machineId int64) (any, error) {
+	path = strings.NewReplacer(
+		"{shopId}", url.PathEscape(shopId),
+		"{machineId}", strconv.FormatInt(machineId, 10),
+	).Replace(path)
+	u := strings.TrimSuffix(v.cfg.BaseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range v.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("接口返回 HTTP %d", resp.StatusCode)
+	}
+
+	// 保留数字原文，避免较长的机器ID丢失精度
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxVendorResponse))
+	decoder.UseNumber()
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("解析响应失败（最大 %d 字节）: %w", maxVendorResponse, err)
+	}
+	return body, nil
+}
+
+// machines 获取商店的全部机器
+func (v *httpJSONVendor) machines(ctx context.Context, shopId string) ([]*httpJSONMachine, error) {
+	body, err := v.get(ctx, v.cfg.MachinesPath, shopId, 0)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := lookup(body, v.cfg.MachinesItems)
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("响应中 %q 不是数组", v.cfg.MachinesItems)
+	}
+
+	machines := make([]*httpJSONMachine, 0, len(items))
+	for i, item := range items {
+		machine, err := v.parseMachine(item)
+		if err != nil {
+			return nil, fmt.Errorf("解析第 %d 台机器失败: %w", i, err)
+		}
+		machines = append(machines, machine)
+	}
+	return machines, nil
+}
+
+// parseMachine 按字段映射解析机器对象
+func (v *httpJSONVendor) parseMachine(obj any) (*httpJSONMachine, error) {
+	fields := v.cfg.Fields
+	field := func(path, name string) string {
+		if path == "" {
+			path = name
+		}
+		value, _ := lookup(obj, path)
+		return text(value)
+	}
+
+	idText := field(fields.Id, "id")
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析机器ID %q", idText)
+	}
+	return &httpJSONMachine{
+		Id:     id,
+		Name:   field(fields.Name, "name"),
+		Type:   field(fields.Type, "type"),
+		Status: field(fields.Status, "status"),
+		Msg:    field(fields.Msg, "msg"),
+	}, nil
+}
+
+func (v *httpJSONVendor) MachineTypes(ctx context.Context, shopId string) ([]MachineType, error) {
+	machines, err := v.machines(ctx, shopId)
+	if err != nil {
+		return nil, err
+	}
+	types := make([]MachineType, 0)
+	seen := make(map[string]bool)
+	for _, machine := range machines {
+		if !seen[machine.Type] {
+			seen[machine.Type] = true
+			types = append(types, MachineType{Id: machine.Type, Name: machine.Type})
+		}
+	}
+	return types, nil
+}
+
+func (v *httpJSONVendor) Machines(ctx context.Context, shopId string, machineType MachineType) ([]MachineInfo, error) {
+	all, err := v.AllMachines(ctx, shopId)
+	if err != nil {
+		return nil, err
+	}
+	if infos := all[machineType.Id]; infos != nil {
+		return infos, nil
+	}
+	return make([]MachineInfo, 0), nil
+}
+
+// AllMachines 机器列表接口一次返回全部类型的机器，按类型分组，避免每种类型重复请求
+func (v *httpJSONVendor) AllMachines(ctx context.Context, shopId string) (map[string][]MachineInfo, error) {
+	machines, err := v.machines(ctx, shopId)
+	if err != nil {
+		return nil, err
+	}
+	grouped := make(map[string][]MachineInfo)
+	for _, machine := range machines {
+		grouped[machine.Type] = append(grouped[machine.Type], MachineInfo{Id: machine.Id, Name: machine.Name})
+	}
+	return grouped, nil
+}
+
+// MachineStatus 只更新名称、状态和说明，状态接口中的类型字段不使用，
+// 机器类型在获取机器列表时确定，平台调整类型后需要重新获取机器列表
+func (v *httpJSONVendor) MachineStatus(ctx context.Context, machine *model.Machine) (*MachineStatus, error) {
+	body, err := v.get(ctx, v.cfg.StatusPath, machine.ShopId, machine.VendorId)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := lookup(body, v.cfg.StatusData)
+	if !ok {
+		return nil, fmt.Errorf("响应中缺少 %q", v.cfg.StatusData)
+	}
+	parsed, err := v.parseMachine(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MachineStatus{Name: parsed.Name, Msg: parsed.Msg}
+	if status.Name == "" {
+		status.Name = machine.Name
+	}
+	code, ok := statusCodes[v.cfg.StatusMap[parsed.Status]]
+	if !ok {
+		// 未配置映射的状态视为离线
+		code = model.MachineCodeOffline
+		if status.Msg == "" {
+			status.Msg = "未知状态 " + parsed.Status
+		}
+	}
+	status.Code = code
+	return status, nil
+}
+
+// lookup 按 . 分隔的路径查找 JSON 值，数组可使用下标，空路径返回值本身
+func lookup(value any, path string) (any, bool) {
+	if path == "" {
+		return value, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// text 将 JSON 值转换为字符串，用于比较状态码和解析ID
+func text(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,204 @@
+package cron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"washwise/config"
+	"washwise/model"
+)
+
+// listRequests 测试平台机器列表接口的请求次数
+var listRequests atomic.Int64
+
+func newTestHTTPJSONVendor(t *testing.T) *httpJSONVendor {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/shops/campus-1/machines":
+			listRequests.Add(1)
+			_, _ = w.Write([]byte(`{"data": {"list": [
+				{"machine_id": 9007199254740993, "label": "1号洗衣机", "kind": "洗衣机", "state": "IDLE"},
+				{"machine_id": "2", "label": "1号烘干机", "kind": "烘干机", "state": "RUNNING"},
+				{"machine_id": 3, "label": "2号洗衣机", "kind": "洗衣机", "state": 7}
+			]}}`))
+		case "/machines/2":
+			_, _ = w.Write([]byte(`{"data": {"machine_id": 2, "label": "1号烘干机", "state": "RUNNING"}}`))
+		case "/machines/3":
+			_, _ = w.Write([]byte(`{"data": {"machine_id": 3, "state": 7, "note": "门未关"}}`))
+		case "/machines/4":
+			// 超出大小限制的响应
+			_, _ = w.Write([]byte(`{"data": {"machine_id": 4, "note": "` + strings.Repeat("x", maxVendorResponse) + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := config.Vendor{
+		Type:          config.VendorHTTPJSON,
+		BaseURL:       srv.URL + "/",
+		Headers:       map[string]string{"Authorization": "Bearer secret"},
+		MachinesPath:  "/shops/{shopId}/machines",
+		MachinesItems: "data.list",
+		StatusPath:    "machines/{machineId}",
+		StatusData:    "data",
+		StatusMap: map[string]string{
+			"IDLE":    config.StatusAvailable,
+			"RUNNING": config.StatusInUse,
+		},
+	}
+	cfg.Fields.Id = "machine_id"
+	cfg.Fields.Name = "label"
+	cfg.Fields.Type = "kind"
+	cfg.Fields.Status = "state"
+	cfg.Fields.Msg = "note"
+	return newHTTPJSONVendor(cfg)
+}
+
+func TestHTTPJSONVendorMachines(t *testing.T) {
+	v := newTestHTTPJSONVendor(t)
+	ctx := context.Background()
+
+	types, err := v.MachineTypes(ctx, "campus-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 2 || types[0].Name != "洗衣机" || types[1].Name != "烘干机" {
+		t.Fatalf("unexpected types %+v", types)
+	}
+
+	machines, err := v.Machines(ctx, "campus-1", types[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(machines) != 2 || machines[0].Id != 9007199254740993 || machines[0].Name != "1号洗衣机" || machines[1].Id != 3 {
+		t.Errorf("unexpected machines %+v", machines)
+	}
+
+	if _, err := v.MachineTypes(ctx, "unknown"); err == nil {
+		t.Error("expected error for unknown shop")
+	}
+}
+
+func TestHTTPJSONVendorStatus(t *testing.T) {
+	v := newTestHTTPJSONVendor(t)
+	ctx := context.Background()
+
+	status, err := v.MachineStatus(ctx, &model.Machine{Id: 12, VendorId: 2, ShopId: "campus-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Code != model.MachineCodeInUse || status.Name != "1号烘干机" || status.ShopId != "" {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	// 未映射的状态视为离线，名称缺失时保留原名称
+	status, err = v.MachineStatus(ctx, &model.Machine{Id: 13, VendorId: 3, ShopId: "campus-1", Name: "2号洗衣机"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Code != model.MachineCodeOffline || status.Msg != "门未关" || status.Name != "2号洗衣机" {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if _, err := v.MachineStatus(ctx, &model.Machine{Id: 14, VendorId: 4, ShopId: "campus-1"}); err == nil {
+		t.Error("expected error for oversized response")
+	}
+}
+
+func TestFetchMachinesListsOncePerShop(t *testing.T) {
+	cfg := config.Default()
+	cfg.Shops = []string{"campus-1"}
+	cfg.ShopVendors = map[string]string{"campus-1": "campus"}
+	config.Set(cfg)
+
+	store := model.NewMemoryStore()
+	tm := InitTaskManager(store, store)
+	tm.vendors["campus"] = newTestHTTPJSONVendor(t)
+	tm.machineTypes["campus-1"] = []MachineType{{Id: "洗衣机", Name: "洗衣机"}, {Id: "烘干机", Name: "烘干机"}}
+
+	before := listRequests.Load()
+	tm.fetchMachines(cfg.Shops)
+	tm.writes.Wait()
+	if n := listRequests.Load() - before; n != 1 {
+		t.Errorf("expected 1 machine list request for 2 types, got %d", n)
+	}
+	washers, _ := store.GetMachinesByShopIDAndType("campus-1", "洗衣机")
+	dryers, _ := store.GetMachinesByShopIDAndType("campus-1", "烘干机")
+	if len(washers) != 2 || len(dryers) != 1 {
+		t.Errorf("expected 2 washers and 1 dryer, got %+v %+v", washers, dryers)
+	}
+}
+
+// staticVendor 返回固定机器列表的测试平台
+type staticVendor struct {
+	machines []MachineInfo
+}
+
+func (v staticVendor) MachineTypes(ctx context.Context, shopId string) ([]MachineType, error) {
+	return []MachineType{{Id: "洗衣机", Name: "洗衣机"}}, nil
+}
+
+func (v staticVendor) Machines(ctx context.Context, shopId string, machineType MachineType) ([]MachineInfo, error) {
+	return v.machines, nil
+}
+
+func (v staticVendor) MachineStatus(ctx context.Context, machine *model.Machine) (*MachineStatus, error) {
+	return &MachineStatus{Code: model.MachineCodeAvailable}, nil
+}
+
+func TestFetchMachinesSameIdsAcrossVendors(t *testing.T) {
+	cfg := config.Default()
+	cfg.Shops = []string{"campus-1", "north"}
+	cfg.ShopVendors = map[string]string{"campus-1": "campus", "north": "north"}
+	config.Set(cfg)
+
+	store := model.NewMemoryStore()
+	tm := InitTaskManager(store, store)
+	tm.vendors["campus"] = newTestHTTPJSONVendor(t)
+	// 与 campus 平台的机器ID 2、3 相同
+	tm.vendors["north"] = staticVendor{machines: []MachineInfo{{Id: 2, Name: "北2号"}, {Id: 3, Name: "北3号"}}}
+	tm.fetchMachineTypes(cfg.Shops)
+	tm.fetchMachines(cfg.Shops)
+	tm.writes.Wait()
+
+	campus, _ := store.GetMachinesByShopID("campus-1")
+	north, _ := store.GetMachinesByShopID("north")
+	if len(campus) != 3 || len(north) != 2 {
+		t.Fatalf("expected 3 campus and 2 north machines, got %+v %+v", campus, north)
+	}
+	ids := make(map[int64]bool)
+	for _, m := range append(campus, north...) {
+		ids[m.Id] = true
+	}
+	if len(ids) != 5 {
+		t.Errorf("expected distinct ids for all machines, got %+v %+v", campus, north)
+	}
+	for _, m := range north {
+		if m.Vendor != "north" || (m.VendorId != 2 && m.VendorId != 3) {
+			t.Errorf("expected north vendor ids 2 and 3, got %+v", m)
+		}
+	}
+}
+
+func TestQiekjStatus(t *testing.T) {
+	for code, want := range map[int]int{
+		0: model.MachineCodeAvailable,
+		1: model.MachineCodeOffline,
+		2: model.MachineCodeInUse,
+		5: model.MachineCodeOffline,
+	} {
+		if got := qiekjStatus(code); got != want {
+			t.Errorf("qiekjStatus(%d) = %d, want %d", code, got, want)
+		}
+	}
+}
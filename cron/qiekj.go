@@ -0,0 +1,74 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"washwise/model"
+)
+
+// qiekj 设备状态码
+const (
+	qiekjAvailable = 0
+	qiekjOffline   = 1
+	qiekjInUse     = 2
+)
+
+// qiekj 内置的 qiekj 洗衣平台
+type qiekj struct{}
+
+func (qiekj) MachineTypes(ctx context.Context, shopId string) ([]MachineType, error) {
+	resp, err := GetMachineTypes(ctx, shopId)
+	if err != nil {
+		return nil, err
+	}
+	types := make([]MachineType, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		types = append(types, MachineType{Id: item.MachineTypeId, Name: item.MachineTypeName})
+	}
+	return types, nil
+}
+
+func (qiekj) Machines(ctx context.Context, shopId string, machineType MachineType) ([]MachineInfo, error) {
+	resp, err := GetMachines(ctx, shopId, machineType.Id, 1000, 1)
+	if err != nil {
+		return nil, err
+	}
+	machines := make([]MachineInfo, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		id, err := strconv.ParseInt(item.Id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无法解析机器ID %q", item.Id)
+		}
+		machines = append(machines, MachineInfo{Id: id, Name: item.Name})
+	}
+	return machines, nil
+}
+
+func (qiekj) MachineStatus(ctx context.Context, machine *model.Machine) (*MachineStatus, error) {
+	detail, err := GetMachineDetail(ctx, machine.VendorId)
+	if err != nil {
+		return nil, err
+	}
+	status := &MachineStatus{
+		Name:   detail.Name,
+		ShopId: detail.ShopId,
+		Code:   qiekjStatus(detail.DeviceErrorCode),
+	}
+	if detail.DeviceErrorMsg != nil {
+		status.Msg = *detail.DeviceErrorMsg
+	}
+	return status, nil
+}
+
+// qiekjStatus 将 qiekj 设备状态码转换为统一状态码，未知状态视为离线
+func qiekjStatus(code int) int {
+	switch code {
+	case qiekjAvailable:
+		return model.MachineCodeAvailable
+	case qiekjInUse:
+		return model.MachineCodeInUse
+	default: // qiekjOffline 及故障等状态
+		return model.MachineCodeOffline
+	}
+}
@@ -2,10 +2,17 @@ package cron
 
 import (
 	"context"
-	"strconv"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"washwise/cache"
 	"washwise/config"
+	"washwise/event"
 	"washwise/model"
 
 	log "github.com/sirupsen/logrus"
@@ -13,13 +20,42 @@ import (
 
 // TaskManager 任务管理器
 type TaskManager struct {
+	// ctx 用于请求接口，停止时等待超时才取消，使进行中的任务能够完成
 	ctx    context.Context
 	cancel context.CancelFunc
+	// done 停止时关闭，定时任务不再开始新的一轮
+	done     chan struct{}
+	doneOnce sync.Once
+
+	// 进行中的任务，任务名 -> 数量
+	jobs       sync.WaitGroup
+	running    map[string]int
+	runningMux sync.Mutex
+	stopped    bool
+
+	// 数据访问
+	machineStore model.MachineStore
+	usageStore   model.UsageStore
+
+	// 洗衣平台，名称 -> 适配器
+	vendors    map[string]Vendor
+	vendorsMux sync.RWMutex
 
 	// 内存存储
-	machineTypes    map[string]*GetMachineTypesResp // shopId -> types
+	machineTypes    map[string][]MachineType // shopId -> types
 	machineTypesMux sync.RWMutex
 
+	// 商店最近一次获取机器类型或机器列表的错误，shopId -> error，获取成功后清除
+	fetchErrors    map[string]error
+	fetchErrorsMux sync.RWMutex
+
+	// 异步写库任务
+	writes        sync.WaitGroup
+	pendingWrites atomic.Int64
+
+	// 配置热重载时新增的商店，由机器列表任务拉取
+	addedShops chan []string
+
 	// Tickers
 	typeTicker    *time.Ticker
 	machineTicker *time.Ticker
@@ -29,12 +65,19 @@ type TaskManager struct {
 var tm *TaskManager
 
 // InitTaskManager 初始化任务管理器
-func InitTaskManager() *TaskManager {
+func InitTaskManager(machines model.MachineStore, usages model.UsageStore) *TaskManager {
 	ctx, cancel := context.WithCancel(context.Background())
 	tm = &TaskManager{
 		ctx:          ctx,
 		cancel:       cancel,
-		machineTypes: make(map[string]*GetMachineTypesResp),
+		done:         make(chan struct{}),
+		running:      make(map[string]int),
+		machineStore: machines,
+		usageStore:   usages,
+		vendors:      newVendors(config.Get()),
+		machineTypes: make(map[string][]MachineType),
+		fetchErrors:  make(map[string]error),
+		addedShops:   make(chan []string, 1),
 	}
 	return tm
 }
@@ -46,21 +89,21 @@ func GetTaskManager() *TaskManager {
 
 // Start 启动所有定时任务
 func (tm *TaskManager) Start() {
-	go func() {
-		cfg := config.Get()
+	cfg := config.Get()
+	tm.typeTicker = time.NewTicker(config.GetMachineTypesInterval())
+	tm.machineTicker = time.NewTicker(config.GetMachinesInterval())
+	tm.detailTicker = time.NewTicker(config.GetMachineDetailsInterval())
 
+	go func() {
 		// 立即执行一次初始化
-		log.Info("开始初始化数据...")
-		tm.fetchMachineTypes()
-		tm.fetchMachines()
-		tm.fetchMachineDetails()
-		log.Info("数据初始化完成")
+		if finish, ok := tm.begin("init"); ok {
+			log.Info("开始初始化数据...")
+			tm.RunOnce(cfg.Shops)
+			log.Info("数据初始化完成")
+			finish()
+		}
 
 		// 启动定时任务
-		tm.typeTicker = time.NewTicker(config.GetMachineTypesInterval())
-		tm.machineTicker = time.NewTicker(config.GetMachinesInterval())
-		tm.detailTicker = time.NewTicker(config.GetMachineDetailsInterval())
-
 		go tm.runMachineTypesTask()
 		go tm.runMachinesTask()
 		go tm.runMachineDetailsTask()
@@ -73,30 +116,164 @@ func (tm *TaskManager) Start() {
 	}()
 }
 
-// Stop 停止所有定时任务
-func (tm *TaskManager) Stop() {
-	log.Info("正在停止定时任务...")
-	tm.cancel()
-	if tm.typeTicker != nil {
-		tm.typeTicker.Stop()
+// ApplyConfig 应用重新加载的配置：调整任务周期和洗衣平台，拉取新增或更换平台的商店并清理移除商店的机器类型
+func (tm *TaskManager) ApplyConfig(old, new *config.Config) {
+	if old.Cron.Enabled != new.Cron.Enabled {
+		log.Warn("cron.enabled 配置变更需要重启服务才能生效")
+	}
+	if !reflect.DeepEqual(old.Vendors, new.Vendors) {
+		tm.vendorsMux.Lock()
+		tm.vendors = newVendors(new)
+		tm.vendorsMux.Unlock()
+		log.WithField("vendors", slices.Sorted(maps.Keys(new.Vendors))).Info("洗衣平台配置已更新")
 	}
-	if tm.machineTicker != nil {
-		tm.machineTicker.Stop()
+	if tm.typeTicker == nil {
+		return
 	}
-	if tm.detailTicker != nil {
-		tm.detailTicker.Stop()
+
+	for _, t := range []struct {
+		ticker   *time.Ticker
+		old, new config.Duration
+		name     string
+	}{
+		{tm.typeTicker, old.Cron.MachineTypesInterval, new.Cron.MachineTypesInterval, "machine_types_interval"},
+		{tm.machineTicker, old.Cron.MachinesInterval, new.Cron.MachinesInterval, "machines_interval"},
+		{tm.detailTicker, old.Cron.MachineDetailsInterval, new.Cron.MachineDetailsInterval, "machine_details_interval"},
+	} {
+		if t.old != t.new {
+			t.ticker.Reset(t.new.Duration())
+			log.WithField(t.name, t.new).Info("定时任务周期已调整")
+		}
+	}
+
+	var added, switched []string
+	for _, shopId := range new.Shops {
+		if !slices.Contains(old.Shops, shopId) {
+			added = append(added, shopId)
+		} else if vendor := new.ShopVendor(shopId); vendor != old.ShopVendor(shopId) || !reflect.DeepEqual(old.Vendors[vendor], new.Vendors[vendor]) {
+			switched = append(switched, shopId)
+		}
+	}
+	tm.machineTypesMux.Lock()
+	for _, shopId := range old.Shops {
+		if !slices.Contains(new.Shops, shopId) {
+			delete(tm.machineTypes, shopId)
+			log.WithField("shopId", shopId).Info("商店已移除，停止获取数据")
+		}
+	}
+	// 更换平台的商店需要重新获取机器类型和机器列表，旧平台的机器类型不再适用
+	for _, shopId := range switched {
+		delete(tm.machineTypes, shopId)
+		log.WithFields(log.Fields{
+			"shopId": shopId,
+			"vendor": new.ShopVendor(shopId),
+		}).Info("商店的洗衣平台已变更，重新获取数据")
+	}
+	tm.machineTypesMux.Unlock()
+	added = append(added, switched...)
+
+	if len(added) > 0 {
+		log.WithField("shops", added).Info("新增商店，开始获取数据")
+		// 合并尚未处理的新增商店
+		select {
+		case pending := <-tm.addedShops:
+			added = append(pending, added...)
+		default:
+		}
+		tm.addedShops <- added
 	}
-	log.Info("定时任务已停止")
+}
+
+// Stop 停止所有定时任务，不再开始新的一轮，等待进行中的任务和写库完成，最多等待 cron.shutdown_timeout
+// 超时后取消进行中的请求，返回的错误中列出未完成的任务和写库数量
+func (tm *TaskManager) Stop() error {
+	timeout := config.Get().Cron.ShutdownTimeout.Duration()
+	log.WithField("timeout", timeout).Info("正在停止定时任务...")
+
+	tm.runningMux.Lock()
+	tm.stopped = true
+	tm.runningMux.Unlock()
+	tm.doneOnce.Do(func() { close(tm.done) })
+	for _, ticker := range []*time.Ticker{tm.typeTicker, tm.machineTicker, tm.detailTicker} {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		// 写库任务只在任务中添加，任务全部结束后不会再增加
+		tm.jobs.Wait()
+		tm.writes.Wait()
+		close(finished)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-finished:
+		tm.cancel()
+		log.Info("定时任务已停止")
+		return nil
+	case <-timer.C:
+	}
+
+	jobs, writes := tm.runningJobs(), tm.pendingWrites.Load()
+	tm.cancel()
+	log.WithFields(log.Fields{
+		"jobs":   jobs,
+		"writes": writes,
+	}).Warn("等待定时任务完成超时，已取消进行中的请求")
+	return fmt.Errorf("等待定时任务完成超时，放弃任务 [%s] 和 %d 个未完成的写库", strings.Join(jobs, ", "), writes)
+}
+
+// begin 登记开始一个任务，已停止时返回 false，任务结束时调用返回的函数
+func (tm *TaskManager) begin(name string) (func(), bool) {
+	tm.runningMux.Lock()
+	defer tm.runningMux.Unlock()
+	if tm.stopped {
+		return nil, false
+	}
+	tm.jobs.Add(1)
+	tm.running[name]++
+	return func() {
+		tm.runningMux.Lock()
+		tm.running[name]--
+		if tm.running[name] == 0 {
+			delete(tm.running, name)
+		}
+		tm.runningMux.Unlock()
+		tm.jobs.Done()
+	}, true
+}
+
+// runningJobs 进行中的任务名
+func (tm *TaskManager) runningJobs() []string {
+	tm.runningMux.Lock()
+	defer tm.runningMux.Unlock()
+	return slices.Sorted(maps.Keys(tm.running))
+}
+
+// runJob 在未停止时执行一次任务
+func (tm *TaskManager) runJob(name string, job func()) {
+	finish, ok := tm.begin(name)
+	if !ok {
+		return
+	}
+	defer finish()
+	job()
 }
 
 // runMachineTypesTask 运行机器类型获取任务（大周期）
 func (tm *TaskManager) runMachineTypesTask() {
 	for {
 		select {
-		case <-tm.ctx.Done():
+		case <-tm.done:
 			return
 		case <-tm.typeTicker.C:
-			tm.fetchMachineTypes()
+			tm.runJob("machine_types", func() {
+				tm.fetchMachineTypes(config.Get().Shops)
+			})
 		}
 	}
 }
@@ -105,10 +282,21 @@ func (tm *TaskManager) runMachineTypesTask() {
 func (tm *TaskManager) runMachinesTask() {
 	for {
 		select {
-		case <-tm.ctx.Done():
+		case <-tm.done:
 			return
 		case <-tm.machineTicker.C:
-			tm.fetchMachines()
+			tm.runJob("machines", func() {
+				tm.fetchMachines(config.Get().Shops)
+				tm.writes.Wait()
+			})
+		case shops := <-tm.addedShops:
+			// 与定期任务在同一协程执行，避免并发等待写库任务
+			shops = slices.DeleteFunc(shops, func(shopId string) bool {
+				return !slices.Contains(config.Get().Shops, shopId)
+			})
+			tm.runJob("added_shops", func() {
+				tm.RunOnce(shops)
+			})
 		}
 	}
 }
@@ -117,47 +305,63 @@ func (tm *TaskManager) runMachinesTask() {
 func (tm *TaskManager) runMachineDetailsTask() {
 	for {
 		select {
-		case <-tm.ctx.Done():
+		case <-tm.done:
 			return
 		case <-tm.detailTicker.C:
-			tm.fetchMachineDetails()
+			tm.runJob("machine_details", func() {
+				tm.fetchMachineDetails(config.Get().Shops)
+			})
 		}
 	}
 }
 
-// fetchMachineTypes 获取所有商店的机器类型
-func (tm *TaskManager) fetchMachineTypes() {
-	cfg := config.Get()
+// RunOnce 依次执行一轮机器类型、机器列表和机器详情的获取，等待全部写入完成后返回
+func (tm *TaskManager) RunOnce(shops []string) {
+	tm.fetchMachineTypes(shops)
+	tm.fetchMachines(shops)
+	tm.writes.Wait()
+	tm.fetchMachineDetails(shops)
+}
+
+// fetchMachineTypes 获取指定商店的机器类型
+func (tm *TaskManager) fetchMachineTypes(shops []string) {
 	begin := time.Now()
 	log.Info("开始获取机器类型...")
 
-	for _, shopId := range cfg.Shops {
-		resp, err := GetMachineTypes(tm.ctx, shopId)
+	for _, shopId := range shops {
+		vendor, err := tm.vendor(shopId)
 		if err != nil {
 			log.WithError(err).WithField("shopId", shopId).Error("获取机器类型失败")
+			tm.setFetchError(shopId, err)
 			continue
 		}
+		types, err := vendor.MachineTypes(tm.ctx, shopId)
+		if err != nil {
+			log.WithError(err).WithField("shopId", shopId).Error("获取机器类型失败")
+			tm.setFetchError(shopId, err)
+			continue
+		}
+		tm.setFetchError(shopId, nil)
 
 		tm.machineTypesMux.Lock()
-		tm.machineTypes[shopId] = resp
+		tm.machineTypes[shopId] = types
 		tm.machineTypesMux.Unlock()
 		duration := float64(time.Since(begin).Milliseconds()) / 1000.0
 		log.WithFields(log.Fields{
 			"shopId": shopId,
-			"count":  len(resp.Items),
+			"count":  len(types),
 		}).Infof("获取机器类型成功，耗时 %.2fs", duration)
 	}
 }
 
-// fetchMachines 获取所有商店、所有类型的机器列表
-func (tm *TaskManager) fetchMachines() {
-	cfg := config.Get()
+// fetchMachines 获取指定商店所有类型的机器列表
+func (tm *TaskManager) fetchMachines(shops []string) {
 	begin := time.Now()
 	log.Info("开始获取机器列表...")
 
 	totalCount := 0
 
-	for _, shopId := range cfg.Shops {
+	for _, shopId := range shops {
 		// 获取该商店的机器类型
 		tm.machineTypesMux.RLock()
 		types, exists := tm.machineTypes[shopId]
@@ -167,64 +371,101 @@ func (tm *TaskManager) fetchMachines() {
 			log.WithField("shopId", shopId).Warn("未找到机器类型，跳过")
 			continue
 		}
+		vendorName := config.Get().ShopVendor(shopId)
+		vendor, err := tm.vendorNamed(vendorName)
+		if err != nil {
+			log.WithError(err).WithField("shopId", shopId).Error("获取机器列表失败")
+			tm.setFetchError(shopId, err)
+			continue
+		}
 
-		// 遍历所有机器类型
-		for _, machineType := range types.Items {
-			resp, err := GetMachines(tm.ctx, shopId, machineType.MachineTypeId, 1000, 1)
-			if err != nil {
+		// 支持一次获取全部机器的平台每个商店只请求一次，再按类型分组
+		var grouped map[string][]MachineInfo
+		if lister, ok := vendor.(machineLister); ok {
+			if grouped, err = lister.AllMachines(tm.ctx, shopId); err != nil {
+				log.WithError(err).WithField("shopId", shopId).Error("获取机器列表失败")
+				tm.setFetchError(shopId, err)
+				continue
+			}
+		}
+
+		// 遍历所有机器类型，记录最后一个失败的类型
+		var fetchErr error
+		for _, machineType := range types {
+			var items []MachineInfo
+			if grouped != nil {
+				items = grouped[machineType.Id]
+			} else if items, err = vendor.Machines(tm.ctx, shopId, machineType); err != nil {
 				log.WithError(err).WithFields(log.Fields{
 					"shopId":        shopId,
-					"machineTypeId": machineType.MachineTypeId,
+					"machineTypeId": machineType.Id,
 				}).Error("获取机器列表失败")
+				fetchErr = err
 				continue
 			}
 
-			if len(resp.Items) == 0 {
+			if len(items) == 0 {
 				continue
 			}
 
 			// 将机器数据持久化到数据库
-			machines := make([]model.Machine, 0, len(resp.Items))
-			for _, item := range resp.Items {
-				id, _ := strconv.ParseInt(item.Id, 10, 64)
+			machines := make([]model.Machine, 0, len(items))
+			for _, item := range items {
 				machines = append(machines, model.Machine{
-					Id:     id,
-					Name:   item.Name,
-					Code:   model.MachineCodeOffline,
-					ShopId: shopId,
-					Type:   machineType.MachineTypeName,
+					Vendor:   vendorName,
+					VendorId: item.Id,
+					Name:     item.Name,
+					Code:     model.MachineCodeOffline,
+					ShopId:   shopId,
+					Type:     machineType.Name,
 				})
 			}
 			totalCount += len(machines)
+			tm.writes.Add(1)
+			tm.pendingWrites.Add(1)
 			go func() {
-				err := model.InsertMachinesIfNotExists(machines)
+				defer func() {
+					tm.pendingWrites.Add(-1)
+					tm.writes.Done()
+				}()
+				err := tm.machineStore.InsertMachinesIfNotExists(machines)
 				if err != nil {
 					log.WithError(err).WithFields(log.Fields{
 						"shopId":        shopId,
-						"machineTypeId": machineType.MachineTypeId,
+						"machineTypeId": machineType.Id,
 					}).Error("持久化机器列表失败")
+					return
 				}
+				// 新增的机器会出现在机器列表中
+				cache.Invalidate(shopId)
 			}()
 		}
+		tm.setFetchError(shopId, fetchErr)
 	}
 
 	duration := float64(time.Since(begin).Milliseconds()) / 1000.0
 	log.WithField("count", totalCount).Infof("获取机器列表完成，耗时 %.2fs", duration)
 }
 
-// fetchMachineDetails 获取所有机器的详情
-func (tm *TaskManager) fetchMachineDetails() {
+// fetchMachineDetails 获取指定商店所有机器的详情
+func (tm *TaskManager) fetchMachineDetails(shops []string) {
 	begin := time.Now()
 	log.Info("开始获取机器详情...")
 
 	// 从数据库获取所有机器
-	machines, err := model.GetAllMachines()
+	machines, err := tm.machineStore.GetAllMachines()
 	if err != nil {
 		log.WithError(err).Error("从数据库获取机器列表失败")
 		return
 	}
+	machines = slices.DeleteFunc(machines, func(m *model.Machine) bool {
+		return !slices.Contains(shops, m.ShopId)
+	})
 
 	successCount := 0
+	// 写入变更的商店，本轮结束后使其响应缓存失效
+	changedShops := make(map[string]bool)
+	var changedMux sync.Mutex
 	wg := sync.WaitGroup{}
 	wg.Add(len(machines))
 	sem := make(chan struct{}, 3) // 限制并发数为3
@@ -234,7 +475,14 @@ func (tm *TaskManager) fetchMachineDetails() {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 			begin := time.Now()
-			detail, err := GetMachineDetail(tm.ctx, machine.Id)
+			before := *machine
+			// 机器ID只在所属平台中有效，按机器记录的平台获取状态
+			vendor, err := tm.vendorNamed(machine.Vendor)
+			if err != nil {
+				log.WithError(err).WithField("machineId", machine.Id).Warn("获取机器详情失败")
+				return
+			}
+			status, err := vendor.MachineStatus(tm.ctx, machine)
 			if err != nil {
 				duration := float64(time.Since(begin).Milliseconds()) / 1000.0
 				log.WithError(err).WithField("machineId", machine.Id).Warnf("获取机器详情失败，耗时 %.2fs", duration)
@@ -242,16 +490,16 @@ func (tm *TaskManager) fetchMachineDetails() {
 			}
 
 			// 当机器状态从可用变为使用中时，更新最后使用时间
-			if machine.Code == model.MachineCodeAvailable && detail.DeviceErrorCode == model.MachineCodeInUse {
+			if machine.Code == model.MachineCodeAvailable && status.Code == model.MachineCodeInUse {
 				machine.LastUseTime = time.Now().Unix()
-			} else if machine.Code == model.MachineCodeInUse && detail.DeviceErrorCode != model.MachineCodeInUse {
+			} else if machine.Code == model.MachineCodeInUse && status.Code != model.MachineCodeInUse {
 				// 当机器状态从使用中变为不可用时，记录使用结束时间，记录入库
 				usage := &model.Usage{
 					MachineId: machine.Id,
 					StartTime: machine.LastUseTime,
 					EndTime:   time.Now().Unix(),
 				}
-				model.CreateUsage(usage)
+				tm.usageStore.CreateUsage(usage)
 				log.WithFields(log.Fields{
 					"mid":      machine.Id,
 					"begin":    time.Unix(usage.StartTime, 0).Format("2006-01-02 15:04:05"),
@@ -262,20 +510,36 @@ func (tm *TaskManager) fetchMachineDetails() {
 			}
 
 			// 更新机器信息
-			machine.Name = detail.Name
-			machine.ShopId = detail.ShopId
-			machine.Code = detail.DeviceErrorCode
-			machine.Msg = ""
-			if detail.DeviceErrorMsg != nil {
-				machine.Msg = *detail.DeviceErrorMsg
+			lastCode := machine.Code
+			machine.Name = status.Name
+			if status.ShopId != "" {
+				machine.ShopId = status.ShopId
 			}
+			machine.Code = status.Code
+			machine.Msg = status.Msg
 
-			if err := model.UpdateMachine(machine); err != nil {
+			if err := tm.machineStore.UpdateMachine(machine); err != nil {
 				duration := float64(time.Since(begin).Milliseconds()) / 1000.0
 				log.WithError(err).WithField("machineId", machine.Id).Warnf("更新机器信息失败，耗时 %.2fs", duration)
 				return
 			}
 
+			if *machine != before {
+				changedMux.Lock()
+				changedShops[before.ShopId] = true
+				changedShops[machine.ShopId] = true
+				changedMux.Unlock()
+			}
+
+			if lastCode != machine.Code {
+				event.Publish(event.Event{
+					Type:      event.TypeMachineStatus,
+					ShopId:    machine.ShopId,
+					MachineId: machine.Id,
+					Data:      newMachineStatusData(machine),
+				})
+			}
+
 			duration := float64(time.Since(begin).Milliseconds()) / 1000.0
 			log.WithField("machineId", machine.Id).Debugf("更新机器信息完成，耗时 %.2fs", duration)
 
@@ -283,6 +547,9 @@ func (tm *TaskManager) fetchMachineDetails() {
 		}()
 	}
 	wg.Wait()
+	if len(changedShops) > 0 {
+		cache.Invalidate(slices.Collect(maps.Keys(changedShops))...)
+	}
 
 	duration := float64(time.Since(begin).Milliseconds()) / 1000.0
 	log.WithFields(log.Fields{
@@ -305,9 +572,37 @@ func calculateAvgUseTime(lastAvg, newUseTime int64) int64 {
 	return (lastAvg*9 + newUseTime) / 10 // 简单移动平均
 }
 
+// setFetchError 记录商店最近一次获取的结果，err 为 nil 时清除
+func (tm *TaskManager) setFetchError(shopId string, err error) {
+	tm.fetchErrorsMux.Lock()
+	defer tm.fetchErrorsMux.Unlock()
+	if err == nil {
+		delete(tm.fetchErrors, shopId)
+		return
+	}
+	tm.fetchErrors[shopId] = err
+}
+
+// FetchError 返回商店最近一次从洗衣平台获取机器类型或机器列表的错误，成功或尚未获取时返回 nil
+func (tm *TaskManager) FetchError(shopId string) error {
+	tm.fetchErrorsMux.RLock()
+	defer tm.fetchErrorsMux.RUnlock()
+	return tm.fetchErrors[shopId]
+}
+
 // GetMachineTypes 获取指定商店的机器类型（从内存）
-func (tm *TaskManager) GetMachineTypesFromMemory(shopId string) *GetMachineTypesResp {
+func (tm *TaskManager) GetMachineTypesFromMemory(shopId string) []MachineType {
 	tm.machineTypesMux.RLock()
 	defer tm.machineTypesMux.RUnlock()
 	return tm.machineTypes[shopId]
 }
+
+func newMachineStatusData(machine *model.Machine) *MachineStatusData {
+	return &MachineStatusData{
+		Name:       machine.Name,
+		Type:       machine.Type,
+		Status:     machine.Code,
+		Msg:        machine.Msg,
+		RemainTime: machine.PredictRemainTime(),
+	}
+}
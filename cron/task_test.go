@@ -0,0 +1,55 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"washwise/config"
+)
+
+func TestStopWaitsForJobs(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cron.ShutdownTimeout = config.Duration(time.Second)
+	config.Set(cfg)
+
+	tm := InitTaskManager(nil, nil)
+	finish, ok := tm.begin("machine_details")
+	if !ok {
+		t.Fatal("expected job to begin")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		finish()
+	}()
+	if err := tm.Stop(); err != nil {
+		t.Fatalf("expected stop to wait for job, got %v", err)
+	}
+	if _, ok := tm.begin("machines"); ok {
+		t.Error("expected no new jobs after stop")
+	}
+	// 重复停止不会 panic
+	if err := tm.Stop(); err != nil {
+		t.Errorf("expected second stop to succeed, got %v", err)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cron.ShutdownTimeout = config.Duration(50 * time.Millisecond)
+	config.Set(cfg)
+
+	tm := InitTaskManager(nil, nil)
+	if _, ok := tm.begin("machine_details"); !ok {
+		t.Fatal("expected job to begin")
+	}
+	tm.writes.Add(1)
+	tm.pendingWrites.Add(1)
+
+	err := tm.Stop()
+	if err == nil || !strings.Contains(err.Error(), "machine_details") || !strings.Contains(err.Error(), "1 个") {
+		t.Errorf("expected abandoned job and write in error, got %v", err)
+	}
+	if tm.ctx.Err() == nil {
+		t.Error("expected requests to be canceled after timeout")
+	}
+}
@@ -50,3 +50,12 @@ type GetMachineDetailResp struct {
 	DeviceErrorMsg      *string `json:"deviceErrorMsg"`
 	AnnouncementContent *string `json:"announcementContent"`
 }
+
+// MachineStatusData 机器状态变化事件数据
+type MachineStatusData struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Status     int    `json:"status"`
+	Msg        string `json:"msg"`
+	RemainTime int64  `json:"remainTime"`
+}
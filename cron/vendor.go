@@ -0,0 +1,76 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"washwise/config"
+	"washwise/model"
+)
+
+// MachineType 洗衣平台的机器类型
+type MachineType struct {
+	Id   string
+	Name string // 类型名称，如“洗衣机”，保存为机器的 Type
+}
+
+// MachineInfo 机器列表中的机器
+type MachineInfo struct {
+	Id   int64 // 平台中的机器ID，保存为机器的 VendorId
+	Name string
+}
+
+// MachineStatus 机器当前状态
+type MachineStatus struct {
+	Name   string
+	ShopId string // 平台返回的所属商店，为空表示不变
+	Code   int    // 统一状态码，见 model.MachineCode* 常量
+	Msg    string
+}
+
+// Vendor 洗衣平台适配器，将各平台的接口和状态码转换为统一的机器数据
+type Vendor interface {
+	// MachineTypes 获取商店的机器类型
+	MachineTypes(ctx context.Context, shopId string) ([]MachineType, error)
+	// Machines 获取商店中指定类型的机器
+	Machines(ctx context.Context, shopId string, machineType MachineType) ([]MachineInfo, error)
+	// MachineStatus 获取单台机器的当前状态，按 machine.VendorId 请求平台
+	MachineStatus(ctx context.Context, machine *model.Machine) (*MachineStatus, error)
+}
+
+// machineLister 能一次获取商店全部机器的洗衣平台实现该接口，获取机器列表时每个商店只请求一次，
+// 不再按机器类型分别调用 Machines
+type machineLister interface {
+	// AllMachines 获取商店的全部机器，机器类型ID -> 机器
+	AllMachines(ctx context.Context, shopId string) (map[string][]MachineInfo, error)
+}
+
+// statusCodes 配置中的统一状态 -> 统一状态码
+var statusCodes = map[string]int{
+	config.StatusAvailable: model.MachineCodeAvailable,
+	config.StatusInUse:     model.MachineCodeInUse,
+	config.StatusOffline:   model.MachineCodeOffline,
+}
+
+// newVendors 根据配置创建洗衣平台适配器，qiekj 为内置平台
+func newVendors(cfg *config.Config) map[string]Vendor {
+	vendors := map[string]Vendor{config.VendorQiekj: qiekj{}}
+	for name, v := range cfg.Vendors {
+		vendors[name] = newHTTPJSONVendor(v)
+	}
+	return vendors
+}
+
+// vendor 获取商店使用的洗衣平台
+func (tm *TaskManager) vendor(shopId string) (Vendor, error) {
+	return tm.vendorNamed(config.Get().ShopVendor(shopId))
+}
+
+// vendorNamed 按名称获取洗衣平台
+func (tm *TaskManager) vendorNamed(name string) (Vendor, error) {
+	tm.vendorsMux.RLock()
+	defer tm.vendorsMux.RUnlock()
+	if v, ok := tm.vendors[name]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("未知洗衣平台 %q", name)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"washwise/export"
+	"washwise/model"
+)
+
+const maxImportLine = 1 << 20
+
+// runExport 将数据表逐行导出为 JSON Lines
+func runExport(configPath string, args []string) error {
+	fs := newFlagSet("export", &configPath)
+	out := fs.String("out", "-", "输出文件，- 表示标准输出")
+	tables := fs.String("tables", strings.Join(model.DumpTables, ","), "导出的数据表，以逗号分隔")
+	_ = fs.Parse(args)
+
+	names := strings.Split(*tables, ",")
+	for _, name := range names {
+		if !slices.Contains(model.DumpTables, name) {
+			return fmt.Errorf("未知数据表: %s", name)
+		}
+	}
+
+	if _, err := setup(configPath); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	for _, name := range names {
+		count := 0
+		err := model.Dump(name, func(row any) error {
+			raw, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			count++
+			return enc.Encode(&model.DumpRecord{Table: name, Row: raw})
+		})
+		if err != nil {
+			return fmt.Errorf("导出 %s 失败: %w", name, err)
+		}
+		fmt.Fprintf(os.Stderr, "%s: 导出 %d 行\n", name, count)
+	}
+	return bw.Flush()
+}
+
+// runExportUsages 以 CSV 或 JSON Lines 格式导出使用记录，与 /api/v2/export/usages 输出相同
+func runExportUsages(configPath string, args []string) error {
+	fs := newFlagSet("export-usages", &configPath)
+	out := fs.String("out", "-", "输出文件，- 表示标准输出")
+	format := fs.String("format", export.FormatCSV, "导出格式："+strings.Join(export.Formats, "、"))
+	shopId := fs.String("shop", "", "只导出该商店的使用记录，默认全部")
+	fromStr := fs.String("from", "", "开始时间（含），YYYY-MM-DD 或 RFC3339")
+	toStr := fs.String("to", "", "结束时间，YYYY-MM-DD（含当天）或 RFC3339（不含）")
+	_ = fs.Parse(args)
+
+	if !slices.Contains(export.Formats, *format) {
+		return fmt.Errorf("未知导出格式: %s", *format)
+	}
+
+	// 日期按配置的洗衣房时区解析，需要先加载配置
+	if _, err := setup(configPath); err != nil {
+		return err
+	}
+	from, err := export.ParseTime(*fromStr, false)
+	if err != nil {
+		return fmt.Errorf("--from %w", err)
+	}
+	to, err := export.ParseTime(*toStr, true)
+	if err != nil {
+		return fmt.Errorf("--to %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if *out != "-" {
+		if f, err = os.Create(*out); err != nil {
+			return err
+		}
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	count, err := export.Usages(bw, *format, model.GormStore{}, *shopId, from, to)
+	if err == nil && f != nil {
+		// 写回磁盘失败（如空间不足、网络文件系统）可能到 Sync 或 Close 时才返回，
+		// 先 Sync 使失败时仍能写入错误标记；设备文件不支持 Sync，跳过
+		if info, statErr := f.Stat(); statErr == nil && info.Mode().IsRegular() {
+			err = f.Sync()
+		}
+	}
+	if err != nil {
+		// 与接口导出一致，在末尾写入错误标记
+		_ = export.WriteError(bw, *format, "export failed")
+		_ = bw.Flush()
+	}
+	if f != nil {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "导出使用记录 %d 条\n", count)
+	return nil
+}
+
+// runImport 导入 export 导出的数据或 archive 生成的归档文件（自动识别 gzip 压缩），主键已存在的行跳过
+// 导入的使用记录所在日期的使用汇总会根据使用记录重新计算
+func runImport(configPath string, args []string) error {
+	fs := newFlagSet("import", &configPath)
+	in := fs.String("in", "-", "输入文件，- 表示标准输入")
+	_ = fs.Parse(args)
+
+	if _, err := setup(configPath); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var usageFrom, usageTo int64
+	inserted := make(map[string]int)
+	skipped := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		record := &model.DumpRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("第 %d 行格式错误: %w", line, err)
+		}
+		ok, err := model.Restore(record)
+		if err != nil {
+			return fmt.Errorf("第 %d 行导入失败: %w", line, err)
+		}
+		if ok && record.Table == "usages" {
+			usage := &model.Usage{}
+			_ = json.Unmarshal(record.Row, usage)
+			if usageFrom == 0 || usage.StartTime < usageFrom {
+				usageFrom = usage.StartTime
+			}
+			usageTo = max(usageTo, usage.StartTime+1)
+		}
+		if ok {
+			inserted[record.Table]++
+		} else {
+			skipped[record.Table]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := model.ResetSequences(); err != nil {
+		return err
+	}
+	if inserted["usages"] > 0 {
+		if err := model.RebuildUsageRollups(usageFrom, usageTo); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range model.DumpTables {
+		if inserted[name] > 0 || skipped[name] > 0 {
+			fmt.Printf("%s: 导入 %d 行，跳过已存在 %d 行\n", name, inserted[name], skipped[name])
+		}
+	}
+	return nil
+}
@@ -17,7 +17,7 @@ const docTemplate = `{
     "paths": {
         "/api/v1/getLaundryMachines": {
             "get": {
-                "description": "获取洗衣机列表",
+                "description": "获取洗衣机列表，未配置的店铺返回404",
                 "produces": [
                     "application/json"
                 ],
@@ -73,6 +73,123 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v2/events": {
+            "get": {
+                "description": "Server-Sent Events 推送机器状态变化；带设备标识时同时推送该设备的排队事件",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "事件流",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "店铺ID",
+                        "name": "shopId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "洗衣机ID",
+                        "name": "machineId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "设备标识，也可通过 X-Device-Id 请求头传递",
+                        "name": "deviceId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/export/usages": {
+            "get": {
+                "description": "以 CSV 或 JSON Lines 格式流式导出使用记录，需要管理员令牌，配置 export.public 后公开\n导出中途失败时状态码仍为200，最后一行为错误标记：CSV 为 ` + "`" + `#error,export failed` + "`" + `，JSON Lines 为 ` + "`" + `{\"error\":\"export failed\"}` + "`" + `",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "导出使用记录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "店铺ID，默认全部店铺",
+                        "name": "shopId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间（含），YYYY-MM-DD 或 RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，YYYY-MM-DD（含当天）或 RFC3339（不含）",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "导出格式：csv（默认）或 jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003c管理员令牌\u003e",
+                        "name": "Authorization",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v2/machine/{machineId}": {
             "get": {
                 "description": "获取洗衣机详情",
@@ -96,7 +213,25 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/servicev2.MachineDetailResp"
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_MachineDetailResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
                         }
                     }
                 }
@@ -123,7 +258,99 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/machine/{machineId}/history": {
+            "get": {
+                "description": "按小时、天或周统计洗衣机在时间范围内的使用次数、使用时长和使用率，区间按指定时区对齐",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "获取洗衣机使用历史",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "洗衣机ID",
+                        "name": "machineId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间（含），YYYY-MM-DD 或 RFC3339，默认按粒度取最近1天、7天或8周",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，YYYY-MM-DD（含当天）或 RFC3339（不含），默认当前时间",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "时间粒度：hour（最长31天）、day（默认，最长366天）、week（最长1098天）",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA 时区，如 Asia/Shanghai，默认洗衣房时区（配置项 timezone），与洗衣房时区相差需为整小时",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_HistoryResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
                     }
                 }
             }
@@ -149,14 +376,35 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
                     }
                 }
             }
         },
         "/api/v2/machines": {
             "get": {
-                "description": "获取洗衣机列表",
+                "description": "获取洗衣机列表，店铺还没有机器数据且最近一次从洗衣平台获取失败时返回503 upstream_unavailable",
                 "produces": [
                     "application/json"
                 ],
@@ -171,13 +419,622 @@ const docTemplate = `{
                         "name": "shopId",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "上次响应的 ETag，未变化时返回 304",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_GetMachinesResp"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/me/favorites": {
+            "get": {
+                "description": "获取当前设备收藏的洗衣机和洗衣房",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "获取收藏列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_GetFavoritesResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "收藏洗衣机（传 machineId）或整个洗衣房（只传 shopId），重复收藏返回已有记录",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "添加收藏",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "收藏目标",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.FavoriteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_FavoriteItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/me/favorites/{favoriteId}": {
+            "put": {
+                "description": "修改收藏的备注名",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "修改收藏",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "收藏ID",
+                        "name": "favoriteId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修改内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.UpdateFavoriteReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_FavoriteItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "删除收藏",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "删除收藏",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "收藏ID",
+                        "name": "favoriteId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/me/overview": {
+            "get": {
+                "description": "一次性获取所有收藏的洗衣机（及收藏洗衣房内全部洗衣机）的实时状态和预计剩余时间",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "收藏概览",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_OverviewResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/queue": {
+            "get": {
+                "description": "获取当前设备的排队位置和预计等待时间",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "获取排队状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "店铺ID",
+                        "name": "shopId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "机器类型",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_QueueStatusResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "所有同类型机器都在使用时加入虚拟排队，轮到时通过事件流或 webhook 通知",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "加入排队",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "排队目标",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.QueueReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/servicev2.GetMachinesResp"
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_QueueStatusResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "退出排队",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "退出排队",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "店铺ID",
+                        "name": "shopId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "机器类型",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/queue/claim": {
+            "post": {
+                "description": "收到轮到通知后，在宽限期内认领机器，逾期将让给下一位",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "认领机器",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "设备标识",
+                        "name": "X-Device-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "排队目标",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.QueueReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/shop/{shopId}/history": {
+            "get": {
+                "description": "按小时、天或周统计店铺（可按机器类型筛选）所有机器的使用次数、使用时长和使用率",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "获取店铺使用历史",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "店铺ID",
+                        "name": "shopId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "机器类型，如 洗衣机，默认全部类型",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "开始时间（含），YYYY-MM-DD 或 RFC3339，默认按粒度取最近1天、7天或8周",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "结束时间，YYYY-MM-DD（含当天）或 RFC3339（不含），默认当前时间",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "时间粒度：hour（最长31天）、day（默认，最长366天）、week（最长1098天）",
+                        "name": "granularity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA 时区，如 Asia/Shanghai，默认洗衣房时区（配置项 timezone），与洗衣房时区相差需为整小时",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_HistoryResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
                         }
                     }
                 }
@@ -192,12 +1049,52 @@ const docTemplate = `{
                 "tags": [
                     "v2"
                 ],
-                "summary": "获取店铺列表",
+                "summary": "获取店铺列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-servicev2_GetShopsResp"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/ws": {
+            "get": {
+                "description": "通过消息订阅洗衣房或洗衣机，接收状态变化和排队位置消息。\n客户端消息：{\"op\":\"subscribe|unsubscribe\",\"shopIds\":[],\"machineIds\":[]}、{\"op\":\"ping\"}；\n重连时携带 hello 消息中的 sessionId 可恢复之前的订阅",
+                "tags": [
+                    "v2"
+                ],
+                "summary": "WebSocket 推送",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "会话ID",
+                        "name": "sessionId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "设备标识，用于接收排队消息",
+                        "name": "deviceId",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "101": {
+                        "description": "Switching Protocols"
+                    },
+                    "426": {
+                        "description": "Upgrade Required",
                         "schema": {
-                            "$ref": "#/definitions/servicev2.GetShopsResp"
+                            "$ref": "#/definitions/servicev2.CommonResp-any"
                         }
                     }
                 }
@@ -243,6 +1140,228 @@ const docTemplate = `{
                 }
             }
         },
+        "servicev2.CommonResp-any": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {},
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_FavoriteItem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.FavoriteItem"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_GetFavoritesResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.GetFavoritesResp"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_GetMachinesResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.GetMachinesResp"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_GetShopsResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.GetShopsResp"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_HistoryResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.HistoryResp"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_MachineDetailResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.MachineDetailResp"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_OverviewResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.OverviewResp"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.CommonResp-servicev2_QueueStatusResp": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "成功为0，失败为 HTTP 状态码",
+                    "type": "integer"
+                },
+                "data": {
+                    "$ref": "#/definitions/servicev2.QueueStatusResp"
+                },
+                "error": {
+                    "description": "机器可读的错误码，见 ErrCode*",
+                    "type": "string"
+                },
+                "msg": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.FavoriteItem": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "integer"
+                },
+                "createdAtRfc3339": {
+                    "description": "同 createdAt，洗衣房时区的 RFC3339 格式",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "machineId": {
+                    "description": "为空表示收藏整个洗衣房",
+                    "type": "integer"
+                },
+                "shopId": {
+                    "type": "string"
+                },
+                "shopName": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.FavoriteReq": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "machineId": {
+                    "type": "integer"
+                },
+                "shopId": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.GetFavoritesResp": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/servicev2.FavoriteItem"
+                    }
+                }
+            }
+        },
         "servicev2.GetMachinesResp": {
             "type": "object",
             "properties": {
@@ -305,6 +1424,59 @@ const docTemplate = `{
                 }
             }
         },
+        "servicev2.HistoryBucket": {
+            "type": "object",
+            "properties": {
+                "busyMinutes": {
+                    "description": "区间内开始的使用的总时长，单位分钟",
+                    "type": "integer"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "end": {
+                    "description": "区间结束时间（不含），RFC3339 格式",
+                    "type": "string"
+                },
+                "start": {
+                    "description": "区间开始时间（含），RFC3339 格式",
+                    "type": "string"
+                },
+                "utilization": {
+                    "description": "使用率百分比，即使用时长占区间时长×机器数的比例，最大100",
+                    "type": "number"
+                }
+            }
+        },
+        "servicev2.HistoryResp": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/servicev2.HistoryBucket"
+                    }
+                },
+                "from": {
+                    "description": "统计范围开始时间（含），RFC3339 格式",
+                    "type": "string"
+                },
+                "granularity": {
+                    "type": "string"
+                },
+                "machines": {
+                    "description": "参与统计的机器数",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "to": {
+                    "description": "统计范围结束时间（不含），RFC3339 格式",
+                    "type": "string"
+                }
+            }
+        },
         "servicev2.MachineDetailResp": {
             "type": "object",
             "properties": {
@@ -323,9 +1495,13 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "lastUseTime": {
-                    "description": "上个人开始使用时间",
+                    "description": "上个人开始使用时间，Unix 时间戳",
                     "type": "integer"
                 },
+                "lastUseTimeRfc3339": {
+                    "description": "同 lastUseTime，洗衣房时区的 RFC3339 格式，未使用过时为空",
+                    "type": "string"
+                },
                 "like": {
                     "type": "integer"
                 },
@@ -345,6 +1521,103 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "servicev2.OverviewResp": {
+            "type": "object",
+            "properties": {
+                "shops": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/servicev2.OverviewShop"
+                    }
+                }
+            }
+        },
+        "servicev2.OverviewShop": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "空闲机器数",
+                    "type": "integer"
+                },
+                "favorite": {
+                    "description": "是否收藏了整个洗衣房",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "inUse": {
+                    "description": "使用中机器数",
+                    "type": "integer"
+                },
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/servicev2.GetMachinesRespItem"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.QueueReq": {
+            "type": "object",
+            "properties": {
+                "shopId": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "webhook": {
+                    "description": "轮到时推送通知的地址，可选",
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.QueueStatusResp": {
+            "type": "object",
+            "properties": {
+                "entryId": {
+                    "type": "integer"
+                },
+                "eta": {
+                    "description": "预计轮到的剩余秒数，-1 表示无法预测",
+                    "type": "integer"
+                },
+                "expireAt": {
+                    "description": "已通知时的认领截止时间",
+                    "type": "integer"
+                },
+                "expireAtRfc3339": {
+                    "description": "同 expireAt，洗衣房时区的 RFC3339 格式，未通知时为空",
+                    "type": "string"
+                },
+                "position": {
+                    "description": "前方等待人数+1，已通知时为0",
+                    "type": "integer"
+                },
+                "shopId": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "waiting, notified",
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "servicev2.UpdateFavoriteReq": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,199 @@
+package event
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// 事件类型
+const (
+	TypeMachineStatus = "machine.status" // 机器状态变化
+	TypeQueuePosition = "queue.position" // 排队位置变化
+	TypeQueueTurn     = "queue.turn"     // 轮到排队者使用
+	TypeQueueExpired  = "queue.expired"  // 排队记录过期
+)
+
+const subscriptionBuffer = 64
+
+// Event 推送事件，DeviceId 不为空时只投递给对应设备的订阅者
+type Event struct {
+	Type        string `json:"type"`
+	ShopId      string `json:"shopId,omitempty"`
+	MachineId   int64  `json:"machineId,omitempty"`
+	DeviceId    string `json:"-"`
+	Time        int64  `json:"time"`
+	TimeRfc3339 string `json:"timeRfc3339"` // 同 time，洗衣房时区的 RFC3339 格式
+	Data        any    `json:"data,omitempty"`
+}
+
+// Filter 订阅过滤条件
+type Filter struct {
+	All        bool   // 接收所有非定向事件
+	DeviceId   string // 接收发给该设备的定向事件
+	ShopIds    []string
+	MachineIds []int64
+}
+
+// Match 判断事件是否符合过滤条件
+func (f *Filter) Match(e *Event) bool {
+	if e.DeviceId != "" {
+		return e.DeviceId == f.DeviceId
+	}
+	if f.All {
+		return true
+	}
+	for _, shopId := range f.ShopIds {
+		if shopId == e.ShopId {
+			return true
+		}
+	}
+	for _, machineId := range f.MachineIds {
+		if machineId == e.MachineId {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription 事件订阅，C 在取消订阅或总线关闭后关闭
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	bus    *Bus
+	mu     sync.RWMutex
+	filter Filter
+}
+
+// SetFilter 替换订阅的过滤条件
+func (s *Subscription) SetFilter(filter Filter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+}
+
+// Filter 获取订阅当前的过滤条件
+func (s *Subscription) Filter() Filter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter
+}
+
+func (s *Subscription) match(e *Event) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.Match(e)
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// Bus 进程内事件总线，所有推送通道共享
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	loc    atomic.Pointer[time.Location]
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// SetLocation 设置事件 timeRfc3339 使用的时区，未设置时为进程本地时区
+func (b *Bus) SetLocation(loc *time.Location) {
+	b.loc.Store(loc)
+}
+
+func (b *Bus) location() *time.Location {
+	if loc := b.loc.Load(); loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+// Subscribe 订阅事件
+func (b *Bus) Subscribe(filter Filter) *Subscription {
+	ch := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b, filter: filter}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+func (b *Bus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish 发布事件，订阅者缓冲区满时丢弃该事件，不阻塞发布方
+func (b *Bus) Publish(e Event) {
+	if e.Time == 0 {
+		e.Time = time.Now().Unix()
+	}
+	e.TimeRfc3339 = time.Unix(e.Time, 0).In(b.location()).Format(time.RFC3339)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if !sub.match(&e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			log.WithField("type", e.Type).Warn("订阅者缓冲区已满，丢弃事件")
+		}
+	}
+}
+
+// Close 关闭总线及所有订阅
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		close(sub.ch)
+	}
+	b.subs = make(map[*Subscription]struct{})
+}
+
+var defaultBus = NewBus()
+
+// SetLocation 设置默认总线使用的时区
+func SetLocation(loc *time.Location) {
+	defaultBus.SetLocation(loc)
+}
+
+// Publish 向默认总线发布事件
+func Publish(e Event) {
+	defaultBus.Publish(e)
+}
+
+// Subscribe 订阅默认总线
+func Subscribe(filter Filter) *Subscription {
+	return defaultBus.Subscribe(filter)
+}
+
+// Close 关闭默认总线
+func Close() {
+	defaultBus.Close()
+}
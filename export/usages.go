@@ -0,0 +1,156 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"washwise/model"
+)
+
+// 导出格式
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Formats 支持的导出格式
+var Formats = []string{FormatCSV, FormatJSONL}
+
+// ContentType 导出格式对应的 MIME 类型
+func ContentType(format string) string {
+	if format == FormatJSONL {
+		return "application/x-ndjson; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// UsageRecord JSON Lines 格式导出的一行使用记录
+type UsageRecord struct {
+	MachineId   int64  `json:"machineId"`
+	MachineName string `json:"machineName"`
+	MachineType string `json:"machineType"`
+	ShopId      string `json:"shopId"`
+	StartTime   string `json:"startTime"` // 洗衣房时区的 RFC3339 格式
+	EndTime     string `json:"endTime"`   // 洗衣房时区的 RFC3339 格式
+	Duration    int64  `json:"duration"`  // 使用时长，单位秒
+	Kind        string `json:"kind"`      // 机器种类：washer、dryer 或 other
+}
+
+// csvHeader CSV 格式的表头，与 UsageRecord 字段一一对应
+var csvHeader = []string{"machine_id", "machine_name", "machine_type", "shop_id", "start_time", "end_time", "duration", "kind"}
+
+func newUsageRecord(row *model.UsageExportRow) *UsageRecord {
+	return &UsageRecord{
+		MachineId:   row.MachineId,
+		MachineName: row.MachineName,
+		MachineType: row.MachineType,
+		ShopId:      row.ShopId,
+		StartTime:   model.FormatTime(row.StartTime),
+		EndTime:     model.FormatTime(row.EndTime),
+		Duration:    row.EndTime - row.StartTime,
+		Kind:        row.Kind(),
+	}
+}
+
+// Usages 将 [from, to) 内开始的使用记录按指定格式分批写入 w，返回写入的行数
+// w 实现了 Flush 方法时（如 bufio.Writer）每批写完后调用，使数据尽快发送给客户端
+func Usages(w io.Writer, format string, store model.UsageStore, shopId string, from, to int64) (int64, error) {
+	var write func(r *UsageRecord) error
+	var flush func() error
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return 0, err
+		}
+		write = func(r *UsageRecord) error {
+			return cw.Write([]string{
+				strconv.FormatInt(r.MachineId, 10), r.MachineName, r.MachineType, r.ShopId,
+				r.StartTime, r.EndTime, strconv.FormatInt(r.Duration, 10), r.Kind,
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		write = func(r *UsageRecord) error { return enc.Encode(r) }
+		flush = func() error { return nil }
+	default:
+		return 0, fmt.Errorf("未知导出格式: %s", format)
+	}
+
+	var count int64
+	flushBatch := func() error {
+		if err := flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(interface{ Flush() error }); ok {
+			return f.Flush()
+		}
+		return nil
+	}
+	err := store.ExportUsages(shopId, from, to, func(rows []model.UsageExportRow) error {
+		for i := range rows {
+			if err := write(newUsageRecord(&rows[i])); err != nil {
+				return err
+			}
+			count++
+		}
+		return flushBatch()
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flushBatch()
+}
+
+// ErrorMarker CSV 格式导出中途失败时追加的最后一行的第一列，JSON Lines 格式追加 {"error": 原因}
+const ErrorMarker = "#error"
+
+// WriteError 在已写出的内容末尾追加错误标记，使客户端能区分中途失败与正常结束
+// 流式导出的响应头已经发出，无法再改为错误状态码
+func WriteError(w io.Writer, format, msg string) error {
+	switch format {
+	case FormatJSONL:
+		if err := json.NewEncoder(w).Encode(map[string]string{"error": msg}); err != nil {
+			return err
+		}
+	default:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{ErrorMarker, msg}); err != nil {
+			return err
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// ParseTime 解析导出范围参数，支持 YYYY-MM-DD（洗衣房时区）和 RFC3339 格式，空字符串返回0表示不限
+// 日期作为结束时间时取次日零点，使该日期整天包含在范围内
+func ParseTime(value string, end bool) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if t, err := time.ParseInLocation(model.DayLayout, value, model.Location()); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t.Unix(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式错误 %q，应为 YYYY-MM-DD 或 RFC3339", value)
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"washwise/cron"
+	"washwise/model"
+)
+
+// runFetch 不启动 HTTP 服务，单独执行定时任务
+func runFetch(configPath string, args []string) error {
+	fs := newFlagSet("fetch", &configPath)
+	once := fs.Bool("once", false, "只执行一轮获取后退出")
+	shop := fs.String("shop", "", "只获取指定商店，多个以逗号分隔，默认为配置中的全部商店")
+	_ = fs.Parse(args)
+
+	if *shop != "" && !*once {
+		return fmt.Errorf("--shop 只能与 --once 一起使用")
+	}
+
+	cfg, err := setup(configPath)
+	if err != nil {
+		return err
+	}
+
+	shops := cfg.Shops
+	if *shop != "" {
+		shops = strings.Split(*shop, ",")
+	}
+
+	store := model.GormStore{}
+	taskManager := cron.InitTaskManager(store, store)
+	if *once {
+		begin := time.Now()
+		fmt.Printf("开始获取 %d 个商店的数据...\n", len(shops))
+		taskManager.RunOnce(shops)
+		fmt.Printf("获取完成，耗时 %.2fs，详情见日志\n", time.Since(begin).Seconds())
+		return model.Close()
+	}
+
+	taskManager.Start()
+	fmt.Println("定时任务已启动，按 Ctrl+C 退出")
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+	stopErr := taskManager.Stop()
+	if err := model.Close(); err != nil {
+		return fmt.Errorf("关闭数据库失败: %w", err)
+	}
+	return stopErr
+}
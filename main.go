@@ -1,74 +1,131 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 	"washwise/config"
-	"washwise/cron"
+	"washwise/event"
 	"washwise/model"
-	"washwise/server"
 	"washwise/util"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func main() {
-	if err := config.Load("config/config.yaml"); err != nil {
-		fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
-		os.Exit(1)
-	}
+const (
+	defaultConfigPath = "config/config.yaml"
+	configPathEnv     = "WASHWISE_CONFIG"
+)
 
-	cfg := config.Get()
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(configPath string, args []string) error
+}
 
-	// 使用配置初始化日志系统
-	fmt.Println("正在初始化日志系统...")
-	util.InitLogger(util.LogConfig{
-		Level: cfg.Log.Level,
-		Dir:   cfg.Log.Dir,
-	})
+var commands = []*command{
+	{"serve", "启动 HTTP 服务和定时任务（默认）", runServe},
+	{"fetch", "执行定时任务，--once 只执行一轮", runFetch},
+	{"migrate", "管理数据库结构版本：up、down、status", runMigrate},
+	{"export", "导出数据库数据为 JSON Lines", runExport},
+	{"export-usages", "导出使用记录为 CSV 或 JSON Lines", runExportUsages},
+	{"import", "导入 export 导出的数据或归档文件", runImport},
+	{"archive", "归档超过保留期的数据", runArchive},
+	{"rollup", "根据使用记录重建使用汇总（不含已归档的日期）", runRollup},
+	{"stats", "打印数据统计", runStats},
+	{"check-config", "检查配置文件", runCheckConfig},
+}
 
-	// 初始化数据库
-	log.Info("初始化数据库...")
-	if err := model.InitDB(cfg.Database.Path); err != nil {
-		log.WithError(err).Fatal("初始化数据库失败")
+func main() {
+	configPath := defaultConfigPath
+	if path := os.Getenv(configPathEnv); path != "" {
+		configPath = path
 	}
-	log.Info("数据库初始化成功")
 
-	// 初始化并启动定时任务
-	taskManager := cron.InitTaskManager()
-	if cfg.Cron.Enabled {
-		taskManager.Start()
+	fs := flag.NewFlagSet("washwise", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径，也可通过环境变量 "+configPathEnv+" 指定")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: %s [--config path] <command> [flags]\n\n命令:\n", os.Args[0])
+		for _, cmd := range commands {
+			fmt.Fprintf(fs.Output(), "  %-14s %s\n", cmd.name, cmd.usage)
+		}
+		fmt.Fprintln(fs.Output(), "\n全局参数:")
+		fs.PrintDefaults()
 	}
+	_ = fs.Parse(os.Args[1:])
 
-	// 初始化并启动HTTP服务器
-	log.Info("初始化 HTTP 服务器...")
-	srv := server.New(cfg)
+	// 不带子命令时启动服务，兼容旧的启动方式
+	name, args := "serve", fs.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
 
-	// 在goroutine中启动服务器
-	go func() {
-		if err := srv.Start(); err != nil {
-			log.WithError(err).Fatal("HTTP 服务器启动失败")
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(configPath, args); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
 		}
-	}()
+	}
+
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+	fs.Usage()
+	os.Exit(2)
+}
+
+// newFlagSet 创建子命令参数集，子命令中也可以指定 --config
+func newFlagSet(name string, configPath *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(configPath, "config", *configPath, "配置文件路径")
+	return fs
+}
 
-	// 等待终止信号
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+// loadConfig 加载配置文件
+func loadConfig(path string) (*config.Config, error) {
+	if err := config.Load(path); err != nil {
+		return nil, fmt.Errorf("加载配置文件失败: %w", err)
+	}
+	return config.Get(), nil
+}
 
-	// 服务启动信息直接打印到标准输出
-	fmt.Printf("  WashWise 服务已启动\n")
-	fmt.Printf("  按 Ctrl+C 退出\n")
-	<-sigChan
+// logConfig 日志系统配置
+func logConfig(cfg *config.Config) util.LogConfig {
+	return util.LogConfig{
+		Level:  cfg.Log.Level,
+		Dir:    cfg.Log.Dir,
+		Format: cfg.Log.Format,
+		Stdout: cfg.Log.Stdout,
 
-	// 服务关闭信息直接打印到标准输出
-	fmt.Println("\n收到终止信号，正在关闭服务...")
+		SplitLevels: cfg.Log.SplitLevels,
+		Rotate: util.RotateConfig{
+			Period:   cfg.Log.Rotate.Period,
+			MaxSize:  int64(cfg.Log.Rotate.MaxSize) << 20,
+			Compress: cfg.Log.Rotate.Compress,
+			MaxAge:   cfg.Log.Rotate.MaxAge.Duration(),
+			MaxFiles: cfg.Log.Rotate.MaxFiles,
+		},
+	}
+}
 
-	taskManager.Stop()
-	if err := srv.Stop(); err != nil {
-		fmt.Fprintf(os.Stderr, "关闭 HTTP 服务器失败: %v\n", err)
+// setup 加载配置并初始化日志系统和数据库
+func setup(configPath string) (*config.Config, error) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Println("服务已完全关闭")
+	util.InitLogger(logConfig(cfg))
+
+	model.SetLocation(cfg.Location())
+	event.SetLocation(cfg.Location())
+
+	log.Info("初始化数据库...")
+	if err := model.InitDB(cfg.Database.Driver, cfg.DatabaseDSN()); err != nil {
+		return nil, fmt.Errorf("初始化数据库失败: %w", err)
+	}
+	log.Info("数据库初始化成功")
+	return cfg, nil
 }
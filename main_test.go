@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"washwise/config"
+	"washwise/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+// setupCommandTest 写入时区为 Asia/Shanghai 的配置文件，并把进程本地时区改为 UTC+12，
+// 相当于以不同的 TZ 运行命令；返回配置文件路径
+func setupCommandTest(t *testing.T) string {
+	t.Helper()
+	prevLocal, prevConfig, prevLocation := time.Local, config.Get(), model.Location()
+	t.Cleanup(func() {
+		_ = model.Close()
+		// setup 每次都会添加写入临时目录的日志 Hook
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+		time.Local = prevLocal
+		config.Set(prevConfig)
+		model.SetLocation(prevLocation)
+	})
+	time.Local = time.FixedZone("UTC+12", 12*3600)
+	model.SetLocation(nil)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	data := "timezone: \"Asia/Shanghai\"\nshops: [\"shop\"]\n" +
+		"log:\n  dir: \"" + filepath.Join(dir, "logs") + "\"\n" +
+		"database:\n  path: \"" + filepath.Join(dir, "washwise.db") + "\"\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// insertBoundaryUsages 在洗衣房时区 2025-03-01 零点前后各插入一条使用记录并生成汇总，
+// 之后清除已设置的时区，使随后运行的命令与新进程一样从加载配置开始
+func insertBoundaryUsages(t *testing.T, configPath string) {
+	t.Helper()
+	if _, err := setup(configPath); err != nil {
+		t.Fatal(err)
+	}
+	store := model.GormStore{}
+	if err := store.InsertMachinesIfNotExists([]model.Machine{{Id: 1, Name: "1号", ShopId: "shop", Type: "洗衣机"}}); err != nil {
+		t.Fatal(err)
+	}
+	midnight := time.Date(2025, 3, 1, 0, 0, 0, 0, model.Location()).Unix()
+	for _, start := range []int64{midnight - 1800, midnight + 1800} {
+		if err := store.CreateUsage(&model.Usage{MachineId: 1, StartTime: start, EndTime: start + 600}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := model.RebuildUsageRollups(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	model.SetLocation(nil)
+}
+
+func TestExportUsagesCommandLocation(t *testing.T) {
+	configPath := setupCommandTest(t)
+	insertBoundaryUsages(t, configPath)
+
+	out := filepath.Join(t.TempDir(), "usages.jsonl")
+	args := []string{"-out", out, "-format", "jsonl", "-from", "2025-03-01", "-to", "2025-03-01"}
+	if err := runExportUsages(configPath, args); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var starts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		record := struct{ StartTime string }{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		starts = append(starts, record.StartTime)
+	}
+	// 按洗衣房时区只包含零点后开始的一条
+	if len(starts) != 1 || starts[0] != "2025-03-01T00:30:00+08:00" {
+		t.Errorf("expected only the usage after campus midnight, got %v", starts)
+	}
+}
+
+func TestRollupCommandLocation(t *testing.T) {
+	configPath := setupCommandTest(t)
+	insertBoundaryUsages(t, configPath)
+	if err := model.GetDB().Where("1 = 1").Delete(&model.UsageDaily{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runRollup(configPath, []string{"-since", "2025-03-01"}); err != nil {
+		t.Fatal(err)
+	}
+	// --since 按洗衣房时区解析，零点后开始的使用记录计入当天
+	daily, err := model.GetDailyUsages(1, "2025-02-28", "2025-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(daily) != 1 || daily[0].Day != "2025-03-01" || daily[0].UsageCount != 1 {
+		t.Errorf("expected only 2025-03-01 to be rebuilt with 1 usage, got %+v", daily)
+	}
+}
+
+func TestExportUsagesCommandWriteFailure(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("/dev/full 不可用")
+	}
+	configPath := setupCommandTest(t)
+	// 写入失败时返回错误，不能报告导出成功
+	if err := runExportUsages(configPath, []string{"-out", "/dev/full"}); err == nil {
+		t.Error("expected write failure to be reported")
+	}
+}
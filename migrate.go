@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"washwise/model"
+	"washwise/util"
+)
+
+// runMigrate 管理数据库结构版本
+// migrate [up]：执行所有未执行的迁移，--to 指定目标版本
+// migrate down：回滚最近的迁移，--steps 指定回滚个数
+// migrate status：查看迁移执行状态
+func runMigrate(configPath string, args []string) error {
+	action := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	if !slices.Contains([]string{"up", "down", "status"}, action) {
+		return fmt.Errorf("未知操作 %s，可选 up、down、status", action)
+	}
+
+	fs := newFlagSet("migrate "+action, &configPath)
+	target := fs.Int64("to", 0, "up: 迁移到的目标版本，默认最新版本")
+	steps := fs.Int("steps", 1, "down: 回滚的迁移个数")
+	_ = fs.Parse(args)
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	util.InitLogger(logConfig(cfg))
+	if err := model.Open(cfg.Database.Driver, cfg.DatabaseDSN()); err != nil {
+		return fmt.Errorf("打开数据库失败: %w", err)
+	}
+
+	switch action {
+	case "up":
+		done, err := model.MigrateUp(*target)
+		for _, m := range done {
+			fmt.Printf("已执行 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("没有需要执行的迁移")
+		}
+	case "down":
+		done, err := model.MigrateDown(*steps)
+		for _, m := range done {
+			fmt.Printf("已回滚 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+	case "status":
+		states, current, err := model.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("当前版本: %d\n", current)
+		for _, s := range states {
+			state := "未执行"
+			if s.AppliedAt != 0 {
+				state = "已执行于 " + time.Unix(s.AppliedAt, 0).Format("2006-01-02 15:04:05")
+			}
+			if s.Unknown {
+				state += "（程序中不存在，需升级程序）"
+			}
+			fmt.Printf("  %04d_%-36s %s\n", s.Version, s.Name, state)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+const archiveBatchSize = 1000
+
+// eachBatch 按主键顺序分批读取查询结果，逐批回调
+func eachBatch[T any](query *gorm.DB, fn func(rows []T) error) error {
+	var batch []T
+	var fnErr error
+	result := query.Order("id").FindInBatches(&batch, archiveBatchSize, func(*gorm.DB, int) error {
+		fnErr = fn(batch)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return result.Error
+}
+
+// ExpiredUsages 分批读取 before 之前开始的使用记录
+func ExpiredUsages(before int64, fn func(rows []Usage) error) error {
+	return eachBatch(db.Where("start_time < ?", before), fn)
+}
+
+// DeleteExpiredUsages 删除 before 之前开始且主键不大于 maxId 的使用记录，即已归档的记录
+func DeleteExpiredUsages(before, maxId int64) (int64, error) {
+	result := db.Where("start_time < ? AND id <= ?", before, maxId).Delete(&Usage{})
+	return result.RowsAffected, result.Error
+}
+
+// expiredQueueEntries 已结束且在 before 之前最后更新的排队记录
+func expiredQueueEntries(before int64) *gorm.DB {
+	return db.Where("status NOT IN ? AND updated_at < ?", []int{QueueStatusWaiting, QueueStatusNotified}, before)
+}
+
+// ExpiredQueueEntries 分批读取已结束且在 before 之前最后更新的排队记录
+func ExpiredQueueEntries(before int64, fn func(rows []QueueEntry) error) error {
+	return eachBatch(expiredQueueEntries(before), fn)
+}
+
+// DeleteExpiredQueueEntries 删除已结束且在 before 之前最后更新、主键不大于 maxId 的排队记录
+func DeleteExpiredQueueEntries(before, maxId int64) (int64, error) {
+	result := expiredQueueEntries(before).Where("id <= ?", maxId).Delete(&QueueEntry{})
+	return result.RowsAffected, result.Error
+}
+
+// VerifyUsageRollups 校验 before 之前开始的使用记录都已计入按天汇总，返回汇总不一致的日期
+func VerifyUsageRollups(before int64) ([]string, error) {
+	counts := make(map[UsageDaily]int64)
+	minDay := ""
+	err := ExpiredUsages(before, func(rows []Usage) error {
+		for _, u := range rows {
+			day := UsageDay(u.StartTime)
+			counts[UsageDaily{MachineId: u.MachineId, Day: day}]++
+			if minDay == "" || day < minDay {
+				minDay = day
+			}
+		}
+		return nil
+	})
+	if err != nil || len(counts) == 0 {
+		return nil, err
+	}
+
+	var rows []UsageDaily
+	if err := db.Where("day >= ? AND day <= ?", minDay, UsageDay(before-1)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	rollups := make(map[UsageDaily]int64, len(rows))
+	for _, row := range rows {
+		rollups[UsageDaily{MachineId: row.MachineId, Day: row.Day}] = row.UsageCount
+	}
+
+	var days []string
+	for key, count := range counts {
+		if rollups[key] != count && !slices.Contains(days, key.Day) {
+			days = append(days, key.Day)
+		}
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+// Compact 回收已删除数据占用的空间，目前只对 SQLite 执行 VACUUM
+func Compact() error {
+	if db.Dialector.Name() != DriverSQLite {
+		return nil
+	}
+	return db.Exec("VACUUM").Error
+}
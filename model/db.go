@@ -3,38 +3,64 @@ package model
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// 支持的数据库驱动
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
 var db *gorm.DB
 
-// InitDB 初始化数据库连接并执行自动迁移
-// path: SQLite 数据库文件路径
-func InitDB(path string) error {
-	// 确保数据库文件所在目录存在
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+// InitDB 打开数据库并执行未执行的迁移，数据库结构版本高于程序支持的版本时返回 ErrSchemaTooNew
+// driver: 数据库驱动，见 Driver* 常量
+// dsn: SQLite 为数据库文件路径，其他驱动为连接串
+func InitDB(driver, dsn string) error {
+	if err := Open(driver, dsn); err != nil {
 		return err
 	}
+	_, err := MigrateUp(0)
+	return err
+}
 
-	// 打开数据库连接
-	var err error
-	db, err = gorm.Open(sqlite.Open(path), &gorm.Config{})
+// Open 只打开数据库连接，不执行迁移
+func Open(driver, dsn string) error {
+	dialector, err := openDialector(driver, dsn)
 	if err != nil {
 		return err
 	}
 
-	// 自动迁移数据库结构
-	if err := db.AutoMigrate(&Machine{}, &Usage{}); err != nil {
-		return err
+	// 确保 SQLite 数据库文件所在目录存在
+	if driver == DriverSQLite {
+		path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	// 打开数据库连接
+	db, err = gorm.Open(dialector, &gorm.Config{})
+	return err
 }
 
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
 	return db
 }
+
+// Close 关闭数据库连接
+func Close() error {
+	if db == nil {
+		return nil
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// driver 数据库驱动，根据连接串创建 gorm 方言
+type driver func(dsn string) gorm.Dialector
+
+// drivers 已编译进程序的数据库驱动，各驱动在对应文件的 init 中注册，可通过构建标签裁剪
+var drivers = make(map[string]driver)
+
+func registerDriver(name string, open driver) {
+	drivers[name] = open
+}
+
+// Drivers 返回已编译进程序的数据库驱动名
+func Drivers() []string {
+	return slices.Sorted(maps.Keys(drivers))
+}
+
+func openDialector(name, dsn string) (gorm.Dialector, error) {
+	open, ok := drivers[name]
+	if !ok {
+		return nil, fmt.Errorf("未知数据库驱动 %q，可用: %s", name, strings.Join(Drivers(), ", "))
+	}
+	return open(dsn), nil
+}
@@ -0,0 +1,9 @@
+//go:build !nomysql
+
+package model
+
+import "gorm.io/driver/mysql"
+
+func init() {
+	registerDriver(DriverMySQL, mysql.Open)
+}
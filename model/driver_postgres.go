@@ -0,0 +1,9 @@
+//go:build !nopostgres
+
+package model
+
+import "gorm.io/driver/postgres"
+
+func init() {
+	registerDriver(DriverPostgres, postgres.Open)
+}
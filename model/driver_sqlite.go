@@ -0,0 +1,10 @@
+//go:build cgo && !purego
+
+package model
+
+import "gorm.io/driver/sqlite"
+
+// SQLite，基于 cgo 的 mattn/go-sqlite3
+func init() {
+	registerDriver(DriverSQLite, sqlite.Open)
+}
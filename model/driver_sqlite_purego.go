@@ -0,0 +1,10 @@
+//go:build !cgo || purego
+
+package model
+
+import "github.com/glebarez/sqlite"
+
+// SQLite，纯 Go 实现，CGO_ENABLED=0 或指定 purego 构建标签时使用
+func init() {
+	registerDriver(DriverSQLite, sqlite.Open)
+}
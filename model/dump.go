@@ -0,0 +1,106 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const dumpBatchSize = 500
+
+// DumpTables 可导出导入的数据表，按导入顺序排列
+var DumpTables = []string{"machines", "usages", "favorites", "queue_entries"}
+
+// DumpRecord 导出文件中的一行
+type DumpRecord struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// Dump 分批读取数据表，逐行回调，避免一次性载入内存
+func Dump(table string, fn func(row any) error) error {
+	switch table {
+	case "machines":
+		return dumpTable[Machine](fn)
+	case "usages":
+		return dumpTable[Usage](fn)
+	case "favorites":
+		return dumpTable[Favorite](fn)
+	case "queue_entries":
+		return dumpTable[QueueEntry](fn)
+	default:
+		return fmt.Errorf("未知数据表: %s", table)
+	}
+}
+
+func dumpTable[T any](fn func(row any) error) error {
+	var batch []T
+	var fnErr error
+	result := db.Model(new(T)).Order("id").FindInBatches(&batch, dumpBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if fnErr = fn(&batch[i]); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return result.Error
+}
+
+// Restore 导入一行导出数据，主键已存在时跳过，返回是否插入
+func Restore(record *DumpRecord) (bool, error) {
+	switch record.Table {
+	case "machines":
+		machine := &Machine{}
+		if err := json.Unmarshal(record.Row, machine); err != nil {
+			return false, err
+		}
+		// 增加平台字段前导出的机器都来自 qiekj
+		if machine.Vendor == "" {
+			machine.Vendor, machine.VendorId = LegacyVendor, machine.Id
+		}
+		return insertRow(machine)
+	case "usages":
+		return restoreRow[Usage](record.Row)
+	case "favorites":
+		return restoreRow[Favorite](record.Row)
+	case "queue_entries":
+		return restoreRow[QueueEntry](record.Row)
+	default:
+		return false, fmt.Errorf("未知数据表: %s", record.Table)
+	}
+}
+
+// ResetSequences 导入指定主键的数据后，将 PostgreSQL 自增序列推进到当前最大主键，
+// 避免之后插入时主键冲突；其他数据库的自增值会自动跟随
+func ResetSequences() error {
+	if db.Dialector.Name() != DriverPostgres {
+		return nil
+	}
+	for _, table := range DumpTables {
+		err := db.Exec("SELECT setval(pg_get_serial_sequence(?, 'id'), COALESCE(MAX(id), 0) + 1, false) FROM "+table, table).Error
+		if err != nil {
+			return fmt.Errorf("重置 %s 自增序列失败: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func restoreRow[T any](raw json.RawMessage) (bool, error) {
+	row := new(T)
+	if err := json.Unmarshal(raw, row); err != nil {
+		return false, err
+	}
+	return insertRow(row)
+}
+
+// insertRow 插入一行，与已有数据冲突时跳过，返回是否插入
+func insertRow(row any) (bool, error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,56 @@
+package model
+
+const exportBatchSize = 1000
+
+// UsageExportRow 导出的使用记录，附带机器信息
+type UsageExportRow struct {
+	Id          int64
+	MachineId   int64
+	MachineName string
+	MachineType string
+	ShopId      string
+	StartTime   int64
+	EndTime     int64
+}
+
+// Kind 使用记录对应的机器种类
+func (r *UsageExportRow) Kind() string {
+	return MachineKind(r.MachineType)
+}
+
+// ExportUsages 按主键顺序分批读取 [from, to) 内开始的使用记录及其机器信息，逐批回调
+// shopId 为空时不限商店，from/to 为0时不限时间；每批单独查询，导出期间不会长时间占用数据库连接
+func ExportUsages(shopId string, from, to int64, fn func(rows []UsageExportRow) error) error {
+	var lastId int64
+	for {
+		query := db.Table("usages").
+			Select("usages.id, usages.machine_id, machines.name AS machine_name, machines.type AS machine_type, "+
+				"machines.shop_id, usages.start_time, usages.end_time").
+			Joins("LEFT JOIN machines ON machines.id = usages.machine_id").
+			Where("usages.id > ?", lastId)
+		if shopId != "" {
+			query = query.Where("machines.shop_id = ?", shopId)
+		}
+		if from > 0 {
+			query = query.Where("usages.start_time >= ?", from)
+		}
+		if to > 0 {
+			query = query.Where("usages.start_time < ?", to)
+		}
+
+		var rows []UsageExportRow
+		if err := query.Order("usages.id").Limit(exportBatchSize).Scan(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := fn(rows); err != nil {
+			return err
+		}
+		if len(rows) < exportBatchSize {
+			return nil
+		}
+		lastId = rows[len(rows)-1].Id
+	}
+}
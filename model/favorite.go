@@ -0,0 +1,64 @@
+package model
+
+import "gorm.io/gorm/clause"
+
+// Favorite 设备收藏的洗衣机或洗衣房
+// MachineId 为 0 时表示收藏整个洗衣房
+type Favorite struct {
+	Id        int64  `gorm:"primaryKey,autoIncrement"`
+	DeviceId  string `gorm:"uniqueIndex:idx_favorite_target;size:64"`
+	ShopId    string `gorm:"uniqueIndex:idx_favorite_target;size:64"`
+	MachineId int64  `gorm:"uniqueIndex:idx_favorite_target"`
+	Alias     string
+	CreatedAt int64 `gorm:"autoCreateTime"`
+}
+
+// GetFavoritesByDeviceID 获取设备的所有收藏
+func GetFavoritesByDeviceID(deviceId string) ([]Favorite, error) {
+	var favorites []Favorite
+	err := db.Where("device_id = ?", deviceId).Order("id").Find(&favorites).Error
+	return favorites, err
+}
+
+// GetFavorite 获取设备的单个收藏
+func GetFavorite(deviceId string, id int64) (*Favorite, error) {
+	var favorite Favorite
+	err := db.Where("device_id = ? AND id = ?", deviceId, id).First(&favorite).Error
+	return &favorite, err
+}
+
+// FindFavorite 按收藏目标查找收藏，用于去重
+func FindFavorite(deviceId, shopId string, machineId int64) (*Favorite, error) {
+	var favorite Favorite
+	err := db.Where("device_id = ? AND shop_id = ? AND machine_id = ?", deviceId, shopId, machineId).First(&favorite).Error
+	return &favorite, err
+}
+
+// CreateFavorite 创建收藏，已有相同目标的收藏时（如并发重复提交）不再创建，favorite 替换为已有的收藏
+func CreateFavorite(favorite *Favorite) error {
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "device_id"}, {Name: "shop_id"}, {Name: "machine_id"}},
+		DoNothing: true,
+	}).Create(favorite)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	existing, err := FindFavorite(favorite.DeviceId, favorite.ShopId, favorite.MachineId)
+	if err != nil {
+		return err
+	}
+	*favorite = *existing
+	return nil
+}
+
+// UpdateFavoriteAlias 更新收藏备注名
+func UpdateFavoriteAlias(deviceId string, id int64, alias string) (int64, error) {
+	result := db.Model(&Favorite{}).Where("device_id = ? AND id = ?", deviceId, id).Update("alias", alias)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteFavorite 删除收藏，返回删除的行数
+func DeleteFavorite(deviceId string, id int64) (int64, error) {
+	result := db.Where("device_id = ? AND id = ?", deviceId, id).Delete(&Favorite{})
+	return result.RowsAffected, result.Error
+}
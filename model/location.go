@@ -0,0 +1,41 @@
+package model
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// location 洗衣房所在地的时区，用于按天汇总和日期计算，与进程的 TZ 环境变量无关
+var location atomic.Pointer[time.Location]
+
+// SetLocation 设置洗衣房所在地的时区，应在启动时调用
+// 按天、按小时的使用汇总依赖时区，修改时区后启动时会自动完整重建，见 UsageRollupLocation
+func SetLocation(loc *time.Location) {
+	location.Store(loc)
+}
+
+// Location 洗衣房所在地的时区，未设置时为进程本地时区
+func Location() *time.Location {
+	if loc := location.Load(); loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+// FormatTime 将时间戳格式化为洗衣房时区带偏移的 RFC3339 时间，0 返回空字符串
+func FormatTime(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).In(Location()).Format(time.RFC3339)
+}
+
+// RecentDays 截至 now 所在日期（含）最近 n 天的日期，按时间升序排列
+func RecentDays(now time.Time, n int) []string {
+	now = now.In(Location())
+	days := make([]string, n)
+	for i := range n {
+		days[i] = now.AddDate(0, 0, i-n+1).Format(DayLayout)
+	}
+	return days
+}
@@ -1,18 +1,36 @@
 package model
 
 import (
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// 统一机器状态码，各洗衣平台的状态码由 cron 中的平台适配器转换
 const (
 	MachineCodeAvailable = 0
 	MachineCodeOffline   = 1
 	MachineCodeInUse     = 2
 )
 
+// 机器种类，由机器类型名称推断
+const (
+	MachineKindWasher = "washer"
+	MachineKindDryer  = "dryer"
+	MachineKindOther  = "other"
+)
+
+const defaultUseTime = 45 * 60 // 默认使用时间45分钟，单位秒
+
+// LegacyVendor 增加平台字段前的机器所属的洗衣平台，迁移和导入旧数据时使用
+const LegacyVendor = "qiekj"
+
 type Machine struct {
-	Id          int64 `gorm:"primaryKey"`
+	Id          int64  `gorm:"primaryKey"`                              // 由数据库分配，不同平台的机器不会冲突
+	Vendor      string `gorm:"size:32;uniqueIndex:idx_machines_vendor"` // 洗衣平台名称
+	VendorId    int64  `gorm:"uniqueIndex:idx_machines_vendor"`         // 机器在洗衣平台中的ID，不同平台可能相同
 	Name        string
 	Code        int
 	LastUseTime int64
@@ -24,6 +42,34 @@ type Machine struct {
 	UsageCount  int `gorm:"->;-:migration"` // 非持久化字段
 }
 
+// PredictUseTime 预计单次使用时间，单位秒
+func (m *Machine) PredictUseTime() int64 {
+	if m.AvgUseTime == 0 {
+		return defaultUseTime
+	}
+	return m.AvgUseTime
+}
+
+// PredictRemainTime 根据平均使用时间预测使用中机器的剩余时间，单位秒
+func (m *Machine) PredictRemainTime() int64 {
+	if m.Code != MachineCodeInUse {
+		return 0
+	}
+	return max(m.LastUseTime+m.PredictUseTime()-time.Now().Unix(), 0)
+}
+
+// MachineKind 根据机器类型名称推断机器种类，如“洗衣机”为 washer，“烘干机”为 dryer
+func MachineKind(machineType string) string {
+	switch {
+	case strings.Contains(machineType, "烘") || strings.Contains(machineType, "干衣"):
+		return MachineKindDryer
+	case strings.Contains(machineType, "洗"):
+		return MachineKindWasher
+	default:
+		return MachineKindOther
+	}
+}
+
 // GetMachinesByShopID 根据商店ID获取所有机器
 func GetMachinesByShopID(shopId string) ([]Machine, error) {
 	var machines []Machine
@@ -31,9 +77,21 @@ func GetMachinesByShopID(shopId string) ([]Machine, error) {
 	return machines, err
 }
 
-func GetMachinesWithUsageCount(shopId string, startTime, endTime int64) ([]Machine, error) {
+// GetMachinesByShopIDAndType 根据商店ID和机器类型获取机器
+func GetMachinesByShopIDAndType(shopId, machineType string) ([]Machine, error) {
+	var machines []Machine
+	err := db.Where("shop_id = ? AND type = ?", shopId, machineType).Find(&machines).Error
+	return machines, err
+}
+
+// GetMachinesWithUsageCount 获取商店所有机器，UsageCount 为日期范围内（含首尾）的使用次数，读取自每日汇总
+func GetMachinesWithUsageCount(shopId string, startDay, endDay string) ([]Machine, error) {
 	var machines []Machine
-	err := db.Model(&Machine{}).Select("machines.*, COUNT(usages.id) as usage_count").Joins("LEFT JOIN usages ON machines.id = usages.machine_id AND usages.start_time >= ? AND usages.start_time <= ?", startTime, endTime).Where("machines.shop_id = ?", shopId).Group("machines.id").Find(&machines).Error
+	// 使用相关子查询而非 JOIN + GROUP BY，避免 PostgreSQL/MySQL 对未聚合列的限制
+	usageCount := db.Model(&UsageDaily{}).Select("COALESCE(SUM(usage_count), 0)").
+		Where("usage_daily.machine_id = machines.id AND usage_daily.day >= ? AND usage_daily.day <= ?", startDay, endDay)
+	err := db.Model(&Machine{}).Select("machines.*, (?) AS usage_count", usageCount).
+		Where("machines.shop_id = ?", shopId).Order("machines.id").Find(&machines).Error
 	return machines, err
 }
 
@@ -56,17 +114,21 @@ func UpdateMachine(machine *Machine) error {
 	return db.Save(machine).Error
 }
 
-// InsertMachinesIfNotExists 批量插入机器（如果不存在）
+// InsertMachinesIfNotExists 批量插入机器，同一平台中机器ID已存在的跳过
 func InsertMachinesIfNotExists(machines []Machine) error {
 	if len(machines) == 0 {
 		return nil
 	}
 	return db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "id"}},
+		Columns:   []clause.Column{{Name: "vendor"}, {Name: "vendor_id"}},
 		DoNothing: true,
 	}).Create(&machines).Error
 }
 
-func UpdateMachineLike(machineId int64, value int64) error {
-	return db.Model(&Machine{}).Where("id = ?", machineId).Update("like", gorm.Expr("like + ?", value)).Error
+// UpdateMachineLike 增减机器点赞数，返回更新的行数
+func UpdateMachineLike(machineId int64, value int64) (int64, error) {
+	// like 是 SQL 关键字，通过 clause.Column 按方言加引号
+	like := clause.Column{Name: "like"}
+	result := db.Model(&Machine{}).Where("id = ?", machineId).Update("like", gorm.Expr("? + ?", like, value))
+	return result.RowsAffected, result.Error
 }
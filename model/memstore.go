@@ -0,0 +1,211 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore 内存中的数据访问实现，用于测试，行为与 GormStore 保持一致
+type MemoryStore struct {
+	mu       sync.RWMutex
+	machines map[int64]Machine
+	usages   []Usage
+}
+
+var (
+	_ MachineStore = (*MemoryStore)(nil)
+	_ UsageStore   = (*MemoryStore)(nil)
+)
+
+// NewMemoryStore 创建内存数据访问实现
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{machines: make(map[int64]Machine)}
+}
+
+// sortedMachines 按ID排序返回满足条件的机器
+func (s *MemoryStore) sortedMachines(match func(m *Machine) bool) []Machine {
+	machines := make([]Machine, 0)
+	for _, m := range s.machines {
+		if match(&m) {
+			machines = append(machines, m)
+		}
+	}
+	slices.SortFunc(machines, func(a, b Machine) int { return cmp.Compare(a.Id, b.Id) })
+	return machines
+}
+
+func (s *MemoryStore) GetMachinesByShopID(shopId string) ([]Machine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedMachines(func(m *Machine) bool { return m.ShopId == shopId }), nil
+}
+
+func (s *MemoryStore) GetMachinesByShopIDAndType(shopId, machineType string) ([]Machine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedMachines(func(m *Machine) bool { return m.ShopId == shopId && m.Type == machineType }), nil
+}
+
+func (s *MemoryStore) GetMachinesWithUsageCount(shopId string, startDay, endDay string) ([]Machine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	machines := s.sortedMachines(func(m *Machine) bool { return m.ShopId == shopId })
+	for i := range machines {
+		for _, u := range s.usages {
+			if day := UsageDay(u.StartTime); u.MachineId == machines[i].Id && day >= startDay && day <= endDay {
+				machines[i].UsageCount++
+			}
+		}
+	}
+	return machines, nil
+}
+
+func (s *MemoryStore) GetMachineByID(machineId int64) (*Machine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.machines[machineId]
+	if !ok {
+		return &Machine{}, gorm.ErrRecordNotFound
+	}
+	return &m, nil
+}
+
+func (s *MemoryStore) GetAllMachines() ([]*Machine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	machines := s.sortedMachines(func(*Machine) bool { return true })
+	result := make([]*Machine, 0, len(machines))
+	for i := range machines {
+		result = append(result, &machines[i])
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) UpdateMachine(machine *Machine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := *machine
+	m.UsageCount = 0
+	s.machines[m.Id] = m
+	return nil
+}
+
+func (s *MemoryStore) InsertMachinesIfNotExists(machines []Machine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range machines {
+		var exists bool
+		var maxId int64
+		for id, e := range s.machines {
+			exists = exists || (e.Vendor == m.Vendor && e.VendorId == m.VendorId)
+			maxId = max(maxId, id)
+		}
+		if exists {
+			continue
+		}
+		if m.Id == 0 {
+			m.Id = maxId + 1
+		}
+		m.UsageCount = 0
+		s.machines[m.Id] = m
+	}
+	return nil
+}
+
+func (s *MemoryStore) UpdateMachineLike(machineId int64, value int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.machines[machineId]
+	if !ok {
+		return 0, nil
+	}
+	m.Like += value
+	s.machines[machineId] = m
+	return 1, nil
+}
+
+func (s *MemoryStore) CreateUsage(usage *Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage.Id = int64(len(s.usages)) + 1
+	s.usages = append(s.usages, *usage)
+	return nil
+}
+
+func (s *MemoryStore) GetDailyUsages(machineId int64, startDay, endDay string) ([]UsageDaily, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	daily := make(map[string]*UsageDaily)
+	for _, u := range s.usages {
+		day := UsageDay(u.StartTime)
+		if u.MachineId != machineId || day < startDay || day > endDay {
+			continue
+		}
+		if daily[day] == nil {
+			daily[day] = &UsageDaily{MachineId: machineId, Day: day}
+		}
+		daily[day].UsageCount++
+		daily[day].TotalDuration += u.Duration()
+	}
+	rows := make([]UsageDaily, 0, len(daily))
+	for _, row := range daily {
+		rows = append(rows, *row)
+	}
+	slices.SortFunc(rows, func(a, b UsageDaily) int { return cmp.Compare(a.Day, b.Day) })
+	return rows, nil
+}
+
+func (s *MemoryStore) GetHourlyUsages(machineIds []int64, from, to int64) ([]UsageHourly, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hourly := make(map[int64]*UsageHourly)
+	for _, u := range s.usages {
+		if !slices.Contains(machineIds, u.MachineId) {
+			continue
+		}
+		usageHours(&u, func(hour, count, duration int64) {
+			if hour < from || hour >= to {
+				return
+			}
+			if hourly[hour] == nil {
+				hourly[hour] = &UsageHourly{HourStart: hour}
+			}
+			hourly[hour].UsageCount += count
+			hourly[hour].TotalDuration += duration
+		})
+	}
+	rows := make([]UsageHourly, 0, len(hourly))
+	for _, row := range hourly {
+		rows = append(rows, *row)
+	}
+	slices.SortFunc(rows, func(a, b UsageHourly) int { return cmp.Compare(a.HourStart, b.HourStart) })
+	return rows, nil
+}
+
+func (s *MemoryStore) ExportUsages(shopId string, from, to int64, fn func(rows []UsageExportRow) error) error {
+	s.mu.RLock()
+	var rows []UsageExportRow
+	for _, u := range s.usages {
+		m := s.machines[u.MachineId]
+		if (shopId != "" && m.ShopId != shopId) || (from > 0 && u.StartTime < from) || (to > 0 && u.StartTime >= to) {
+			continue
+		}
+		rows = append(rows, UsageExportRow{
+			Id:          u.Id,
+			MachineId:   u.MachineId,
+			MachineName: m.Name,
+			MachineType: m.Type,
+			ShopId:      m.ShopId,
+			StartTime:   u.StartTime,
+			EndTime:     u.EndTime,
+		})
+	}
+	s.mu.RUnlock()
+	if len(rows) == 0 {
+		return nil
+	}
+	return fn(rows)
+}
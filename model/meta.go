@@ -0,0 +1,36 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// appMeta 程序内部的键值元数据
+type appMeta struct {
+	Name  string `gorm:"primaryKey;size:64"`
+	Value string `gorm:"size:255"`
+}
+
+func (appMeta) TableName() string {
+	return "app_meta"
+}
+
+// getMeta 读取元数据，不存在时返回空字符串
+func getMeta(tx *gorm.DB, name string) (string, error) {
+	var meta appMeta
+	err := tx.Where("name = ?", name).Take(&meta).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return meta.Value, err
+}
+
+// setMeta 写入元数据，已存在时覆盖
+func setMeta(tx *gorm.DB, name, value string) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(&appMeta{Name: name, Value: value}).Error
+}
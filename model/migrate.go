@@ -0,0 +1,254 @@
+package model
+
+import (
+	"cmp"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// migrationFS 各数据库的迁移脚本，文件名格式为 <版本号>_<名称>.up.sql / .down.sql
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+const migrationTable = "schema_migrations"
+
+// ErrSchemaTooNew 数据库结构版本高于程序支持的版本，通常是运行了旧版本程序
+var ErrSchemaTooNew = errors.New("数据库结构版本高于程序支持的版本，请升级程序")
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState 迁移的执行状态
+type MigrationState struct {
+	Version   int64
+	Name      string
+	AppliedAt int64 // 执行时间，未执行时为0
+	Unknown   bool  // 数据库中已执行，但程序中不存在的迁移
+}
+
+// schemaMigration 已执行的迁移记录
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey"`
+	Name      string
+	AppliedAt int64
+}
+
+func (schemaMigration) TableName() string {
+	return migrationTable
+}
+
+// Migrations 返回当前数据库可用的迁移脚本，按版本号升序排列
+func Migrations() ([]Migration, error) {
+	dir := path.Join("migrations", db.Dialector.Name())
+	entries, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("没有 %s 的迁移脚本", db.Dialector.Name())
+	}
+
+	migrations := make(map[int64]*Migration)
+	for _, entry := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		versionStr, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("迁移脚本文件名格式错误: %s", entry.Name())
+		}
+
+		data, err := migrationFS.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, exists := migrations[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			migrations[version] = m
+		}
+		switch direction {
+		case "up":
+			m.Up = string(data)
+		case "down":
+			m.Down = string(data)
+		default:
+			return nil, fmt.Errorf("迁移脚本文件名格式错误: %s", entry.Name())
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, *m)
+	}
+	slices.SortFunc(result, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return result, nil
+}
+
+// appliedMigrations 返回已执行的迁移记录，不存在记录表时自动创建
+func appliedMigrations() ([]schemaMigration, error) {
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS " + migrationTable +
+		" (version bigint PRIMARY KEY, name varchar(255) NOT NULL, applied_at bigint NOT NULL)").Error; err != nil {
+		return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	var applied []schemaMigration
+	err := db.Order("version").Find(&applied).Error
+	return applied, err
+}
+
+// MigrationStatus 返回所有迁移的执行状态和当前结构版本
+func MigrationStatus() ([]MigrationState, int64, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, 0, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var current int64
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		states = append(states, MigrationState{Version: m.Version, Name: m.Name})
+	}
+	for _, a := range applied {
+		current = max(current, a.Version)
+		i := slices.IndexFunc(states, func(s MigrationState) bool { return s.Version == a.Version })
+		if i < 0 {
+			states = append(states, MigrationState{Version: a.Version, Name: a.Name, AppliedAt: a.AppliedAt, Unknown: true})
+			continue
+		}
+		states[i].AppliedAt = a.AppliedAt
+	}
+	slices.SortFunc(states, func(a, b MigrationState) int { return cmp.Compare(a.Version, b.Version) })
+	return states, current, nil
+}
+
+// checkSchema 数据库中存在程序不认识的迁移时拒绝继续
+func checkSchema(states []MigrationState) error {
+	for _, s := range states {
+		if s.Unknown {
+			return fmt.Errorf("%w: 版本 %d（%s）", ErrSchemaTooNew, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// MigrateUp 依次执行未执行的迁移，直到目标版本，target 为0时迁移到最新版本
+// 返回本次执行的迁移
+func MigrateUp(target int64) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	states, _, err := MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchema(states); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if target > 0 && m.Version > target {
+			break
+		}
+		i := slices.IndexFunc(states, func(s MigrationState) bool { return s.Version == m.Version })
+		if states[i].AppliedAt != 0 {
+			continue
+		}
+		err := runMigration(m.Up, func(tx *gorm.DB) error {
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().Unix()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("执行迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		log.WithField("version", m.Version).Infof("已执行迁移 %s", m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown 按版本号倒序回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func MigrateDown(steps int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	states, _, err := MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchema(states); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if states[slices.IndexFunc(states, func(s MigrationState) bool { return s.Version == m.Version })].AppliedAt == 0 {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("迁移 %04d_%s 不支持回滚", m.Version, m.Name)
+		}
+		err := runMigration(m.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&schemaMigration{Version: m.Version}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("回滚迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		log.WithField("version", m.Version).Infof("已回滚迁移 %s", m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// runMigration 在事务中逐条执行迁移脚本并更新迁移记录
+// MySQL 的 DDL 会隐式提交，失败时可能需要手动处理
+func runMigration(script string, record func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// splitStatements 按行尾分号拆分脚本，忽略注释行，迁移脚本中不应在字符串内换行使用分号
+func splitStatements(script string) []string {
+	var stmts []string
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(b.String()))
+			b.Reset()
+		}
+	}
+	if s := strings.TrimSpace(b.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
@@ -0,0 +1,8 @@
+-- 初始表结构，MySQL 不支持 CREATE INDEX IF NOT EXISTS，索引随建表创建
+CREATE TABLE IF NOT EXISTS `machines` (`id` bigint NOT NULL,`name` longtext,`code` bigint,`last_use_time` bigint,`msg` longtext,`avg_use_time` bigint,`shop_id` varchar(191),`type` varchar(191),`like` bigint,PRIMARY KEY (`id`),INDEX `idx_machines_shop_id` (`shop_id`)) DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `usages` (`id` bigint AUTO_INCREMENT,`machine_id` bigint,`start_time` bigint,`end_time` bigint,PRIMARY KEY (`id`),INDEX `idx_usages_start_time` (`start_time`),INDEX `idx_usages_machine_id` (`machine_id`)) DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `favorites` (`id` bigint AUTO_INCREMENT,`device_id` varchar(64),`shop_id` varchar(64),`machine_id` bigint,`alias` longtext,`created_at` bigint,PRIMARY KEY (`id`),UNIQUE INDEX `idx_favorite_target` (`device_id`,`shop_id`,`machine_id`)) DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `queue_entries` (`id` bigint AUTO_INCREMENT,`shop_id` varchar(191),`type` varchar(191),`device_id` varchar(64),`status` bigint,`webhook` longtext,`created_at` bigint,`notified_at` bigint,`expire_at` bigint,`updated_at` bigint,PRIMARY KEY (`id`),INDEX `idx_queue_entries_status` (`status`),INDEX `idx_queue_entries_device_id` (`device_id`),INDEX `idx_queue_target` (`shop_id`,`type`)) DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+CREATE INDEX `idx_usages_machine_id` ON `usages`(`machine_id`);
+DROP INDEX `idx_usages_machine_time` ON `usages`;
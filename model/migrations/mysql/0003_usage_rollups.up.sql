@@ -0,0 +1,4 @@
+-- 使用记录按天、按小时汇总，随使用记录写入增量更新，历史数据由 rollup 命令或启动时回填
+CREATE TABLE IF NOT EXISTS `usage_daily` (`machine_id` bigint NOT NULL,`day` varchar(10) NOT NULL,`usage_count` bigint NOT NULL DEFAULT 0,`total_duration` bigint NOT NULL DEFAULT 0,PRIMARY KEY (`machine_id`,`day`),INDEX `idx_usage_daily_day` (`day`)) DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `usage_hourly` (`machine_id` bigint NOT NULL,`hour_start` bigint NOT NULL,`usage_count` bigint NOT NULL DEFAULT 0,`total_duration` bigint NOT NULL DEFAULT 0,PRIMARY KEY (`machine_id`,`hour_start`),INDEX `idx_usage_hourly_hour_start` (`hour_start`)) DEFAULT CHARSET=utf8mb4;
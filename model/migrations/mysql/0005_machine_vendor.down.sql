@@ -0,0 +1,2 @@
+DROP INDEX `idx_machines_vendor` ON `machines`;
+ALTER TABLE `machines` DROP COLUMN `vendor_id`, DROP COLUMN `vendor`, MODIFY `id` bigint NOT NULL;
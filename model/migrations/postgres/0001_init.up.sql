@@ -0,0 +1,15 @@
+-- 初始表结构
+CREATE TABLE IF NOT EXISTS "machines" ("id" bigint PRIMARY KEY,"name" text,"code" bigint,"last_use_time" bigint,"msg" text,"avg_use_time" bigint,"shop_id" text,"type" text,"like" bigint);
+CREATE INDEX IF NOT EXISTS "idx_machines_shop_id" ON "machines"("shop_id");
+
+CREATE TABLE IF NOT EXISTS "usages" ("id" bigserial PRIMARY KEY,"machine_id" bigint,"start_time" bigint,"end_time" bigint);
+CREATE INDEX IF NOT EXISTS "idx_usages_start_time" ON "usages"("start_time");
+CREATE INDEX IF NOT EXISTS "idx_usages_machine_id" ON "usages"("machine_id");
+
+CREATE TABLE IF NOT EXISTS "favorites" ("id" bigserial PRIMARY KEY,"device_id" varchar(64),"shop_id" varchar(64),"machine_id" bigint,"alias" text,"created_at" bigint);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_favorite_target" ON "favorites"("device_id","shop_id","machine_id");
+
+CREATE TABLE IF NOT EXISTS "queue_entries" ("id" bigserial PRIMARY KEY,"shop_id" text,"type" text,"device_id" varchar(64),"status" bigint,"webhook" text,"created_at" bigint,"notified_at" bigint,"expire_at" bigint,"updated_at" bigint);
+CREATE INDEX IF NOT EXISTS "idx_queue_entries_status" ON "queue_entries"("status");
+CREATE INDEX IF NOT EXISTS "idx_queue_entries_device_id" ON "queue_entries"("device_id");
+CREATE INDEX IF NOT EXISTS "idx_queue_target" ON "queue_entries"("shop_id","type");
@@ -0,0 +1,2 @@
+-- 程序内部的键值元数据，如使用汇总所用的时区
+CREATE TABLE IF NOT EXISTS "app_meta" ("name" varchar(64) NOT NULL,"value" varchar(255) NOT NULL DEFAULT '',PRIMARY KEY ("name"));
@@ -0,0 +1,3 @@
+ALTER TABLE "machines" ALTER COLUMN "id" DROP IDENTITY IF EXISTS;
+DROP INDEX IF EXISTS "idx_machines_vendor";
+ALTER TABLE "machines" DROP COLUMN IF EXISTS "vendor_id", DROP COLUMN IF EXISTS "vendor";
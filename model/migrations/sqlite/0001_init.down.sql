@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `queue_entries`;
+DROP TABLE IF EXISTS `favorites`;
+DROP TABLE IF EXISTS `usages`;
+DROP TABLE IF EXISTS `machines`;
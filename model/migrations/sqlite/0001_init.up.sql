@@ -0,0 +1,15 @@
+-- 初始表结构，与原 AutoMigrate 创建的结构一致，已有数据库上重复执行无影响
+CREATE TABLE IF NOT EXISTS `machines` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`code` integer,`last_use_time` integer,`msg` text,`avg_use_time` integer,`shop_id` text,`type` text,`like` integer);
+CREATE INDEX IF NOT EXISTS `idx_machines_shop_id` ON `machines`(`shop_id`);
+
+CREATE TABLE IF NOT EXISTS `usages` (`id` integer PRIMARY KEY AUTOINCREMENT,`machine_id` integer,`start_time` integer,`end_time` integer);
+CREATE INDEX IF NOT EXISTS `idx_usages_start_time` ON `usages`(`start_time`);
+CREATE INDEX IF NOT EXISTS `idx_usages_machine_id` ON `usages`(`machine_id`);
+
+CREATE TABLE IF NOT EXISTS `favorites` (`id` integer PRIMARY KEY AUTOINCREMENT,`device_id` text,`shop_id` text,`machine_id` integer,`alias` text,`created_at` integer);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_favorite_target` ON `favorites`(`device_id`,`shop_id`,`machine_id`);
+
+CREATE TABLE IF NOT EXISTS `queue_entries` (`id` integer PRIMARY KEY AUTOINCREMENT,`shop_id` text,`type` text,`device_id` text,`status` integer,`webhook` text,`created_at` integer,`notified_at` integer,`expire_at` integer,`updated_at` integer);
+CREATE INDEX IF NOT EXISTS `idx_queue_entries_status` ON `queue_entries`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_queue_entries_device_id` ON `queue_entries`(`device_id`);
+CREATE INDEX IF NOT EXISTS `idx_queue_target` ON `queue_entries`(`shop_id`,`type`);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS `idx_usages_machine_id` ON `usages`(`machine_id`);
+DROP INDEX IF EXISTS `idx_usages_machine_time`;
@@ -0,0 +1,3 @@
+-- 按机器和时间范围统计使用次数时使用联合索引
+CREATE INDEX IF NOT EXISTS `idx_usages_machine_time` ON `usages`(`machine_id`,`start_time`);
+DROP INDEX IF EXISTS `idx_usages_machine_id`;
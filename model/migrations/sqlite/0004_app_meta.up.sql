@@ -0,0 +1,2 @@
+-- 程序内部的键值元数据，如使用汇总所用的时区
+CREATE TABLE IF NOT EXISTS `app_meta` (`name` text NOT NULL,`value` text NOT NULL DEFAULT '',PRIMARY KEY (`name`));
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS `idx_machines_vendor`;
+ALTER TABLE `machines` DROP COLUMN `vendor_id`;
+ALTER TABLE `machines` DROP COLUMN `vendor`;
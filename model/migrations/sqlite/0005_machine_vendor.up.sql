@@ -0,0 +1,6 @@
+-- 机器所属的洗衣平台和平台中的机器ID，不同平台的机器ID可能相同，以两者联合唯一，
+-- id 改为由数据库分配；此前的机器都来自 qiekj，平台机器ID即原来的 id
+ALTER TABLE `machines` ADD COLUMN `vendor` text NOT NULL DEFAULT '';
+ALTER TABLE `machines` ADD COLUMN `vendor_id` integer NOT NULL DEFAULT 0;
+UPDATE `machines` SET `vendor` = 'qiekj', `vendor_id` = `id`;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_machines_vendor` ON `machines`(`vendor`,`vendor_id`);
@@ -0,0 +1,461 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 设置以下环境变量后同时在对应数据库上运行测试，未设置时跳过并在 -v 输出中说明，例如：
+// WASHWISE_TEST_POSTGRES_DSN="host=127.0.0.1 user=postgres password=postgres dbname=washwise_test sslmode=disable"
+// WASHWISE_TEST_MYSQL_DSN="root:root@tcp(127.0.0.1:3306)/washwise_test"
+// 执行 make test-db 会通过 compose.yaml 启动测试数据库并设置好这些变量
+var testDSNEnvs = map[string]string{
+	DriverPostgres: "WASHWISE_TEST_POSTGRES_DSN",
+	DriverMySQL:    "WASHWISE_TEST_MYSQL_DSN",
+}
+
+// forEachDriver 在每个可用的数据库上初始化空库并运行测试
+func forEachDriver(t *testing.T, fn func(t *testing.T)) {
+	for _, driver := range Drivers() {
+		t.Run(driver, func(t *testing.T) {
+			dsn := filepath.Join(t.TempDir(), "washwise.db")
+			if driver != DriverSQLite {
+				dsn = os.Getenv(testDSNEnvs[driver])
+				if dsn == "" {
+					t.Skipf("未设置 %s，跳过 %s 上的测试；执行 make test-db 可启动测试数据库并运行", testDSNEnvs[driver], driver)
+				}
+			}
+			if err := InitDB(driver, dsn); err != nil {
+				t.Fatal(err)
+			}
+			for _, table := range append([]string{"usage_daily", "usage_hourly", "app_meta"}, DumpTables...) {
+				if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Cleanup(func() {
+				if sqlDB, err := db.DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+			fn(t)
+		})
+	}
+}
+
+func TestMachineQueries(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		machines := []Machine{
+			{Id: 1, Vendor: LegacyVendor, VendorId: 1, Name: "1号", ShopId: "shop", Type: "洗衣机", Code: MachineCodeAvailable},
+			{Id: 2, Vendor: LegacyVendor, VendorId: 2, Name: "2号", ShopId: "shop", Type: "洗衣机", Code: MachineCodeInUse},
+			{Id: 3, Vendor: LegacyVendor, VendorId: 3, Name: "3号", ShopId: "other", Type: "洗衣机"},
+		}
+		if err := InsertMachinesIfNotExists(machines); err != nil {
+			t.Fatal(err)
+		}
+		// 重复插入时跳过
+		if err := InsertMachinesIfNotExists(machines[:1]); err != nil {
+			t.Fatal(err)
+		}
+
+		base := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local).Unix()
+		for _, usage := range []Usage{
+			{MachineId: 1, StartTime: base, EndTime: base + 2400},
+			{MachineId: 1, StartTime: base - 86400, EndTime: base - 86400 + 2400},
+			{MachineId: 1, StartTime: base + 3*86400, EndTime: base + 3*86400 + 2400}, // 超出统计范围
+			{MachineId: 3, StartTime: base, EndTime: base + 2400},
+		} {
+			if err := CreateUsage(&usage); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := GetMachinesWithUsageCount("shop", UsageDay(base-86400), UsageDay(base))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0].Id != 1 || got[1].Id != 2 {
+			t.Fatalf("expected machines 1 and 2, got %+v", got)
+		}
+		if got[0].UsageCount != 2 || got[1].UsageCount != 0 {
+			t.Errorf("expected usage count 2 and 0, got %d and %d", got[0].UsageCount, got[1].UsageCount)
+		}
+
+		var exported []UsageExportRow
+		err = ExportUsages("shop", base-86400, base+86400, func(rows []UsageExportRow) error {
+			exported = append(exported, rows...)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(exported) != 2 || exported[0].MachineName != "1号" || exported[0].Kind() != MachineKindWasher {
+			t.Errorf("unexpected exported usages %+v", exported)
+		}
+
+		for _, delta := range []int64{1, 1, -1} {
+			if rows, err := UpdateMachineLike(1, delta); err != nil || rows != 1 {
+				t.Fatalf("update like: rows=%d err=%v", rows, err)
+			}
+		}
+		if rows, err := UpdateMachineLike(404, 1); err != nil || rows != 0 {
+			t.Errorf("expected no rows for unknown machine, got rows=%d err=%v", rows, err)
+		}
+		machine, err := GetMachineByID(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if machine.Like != 1 {
+			t.Errorf("expected like 1, got %d", machine.Like)
+		}
+
+		stats, err := CountMachinesByShopAndCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 3 {
+			t.Errorf("expected 3 stat rows, got %+v", stats)
+		}
+	})
+}
+
+func TestFavoriteAndQueueQueries(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		favorite := &Favorite{DeviceId: "device", ShopId: "shop", MachineId: 1}
+		if err := CreateFavorite(favorite); err != nil {
+			t.Fatal(err)
+		}
+		// 重复收藏不违反唯一索引，返回已有的收藏
+		duplicate := &Favorite{DeviceId: "device", ShopId: "shop", MachineId: 1, Alias: "重复"}
+		if err := CreateFavorite(duplicate); err != nil || duplicate.Id != favorite.Id || duplicate.Alias != "" {
+			t.Errorf("expected existing favorite for duplicate, got %+v %v", duplicate, err)
+		}
+		if rows, err := UpdateFavoriteAlias("device", favorite.Id, "楼下"); err != nil || rows != 1 {
+			t.Errorf("update alias: rows=%d err=%v", rows, err)
+		}
+
+		for _, entry := range []QueueEntry{
+			{ShopId: "shop", Type: "洗衣机", DeviceId: "a", Status: QueueStatusWaiting},
+			{ShopId: "shop", Type: "洗衣机", DeviceId: "b", Status: QueueStatusClaimed, ExpireAt: 2000},
+			{ShopId: "shop", Type: "洗衣机", DeviceId: "c", Status: QueueStatusClaimed, ExpireAt: 500},
+			{ShopId: "shop", Type: "烘干机", DeviceId: "d", Status: QueueStatusNotified},
+		} {
+			if err := CreateQueueEntry(&entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries, err := GetDispatchQueueEntries("shop", "洗衣机", 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || entries[0].DeviceId != "a" || entries[1].DeviceId != "b" {
+			t.Errorf("expected dispatch entries a and b, got %+v", entries)
+		}
+	})
+}
+
+func TestRestore(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		row, _ := json.Marshal(&Usage{Id: 10, MachineId: 1, StartTime: 100, EndTime: 200})
+		record := &DumpRecord{Table: "usages", Row: row}
+		if ok, err := Restore(record); err != nil || !ok {
+			t.Fatalf("restore: ok=%t err=%v", ok, err)
+		}
+		if ok, err := Restore(record); err != nil || ok {
+			t.Fatalf("expected existing row to be skipped: ok=%t err=%v", ok, err)
+		}
+		if err := ResetSequences(); err != nil {
+			t.Fatal(err)
+		}
+
+		// 导入后新插入的记录不能与导入的主键冲突
+		usage := &Usage{MachineId: 1, StartTime: 300, EndTime: 400}
+		if err := CreateUsage(usage); err != nil {
+			t.Fatal(err)
+		}
+		if usage.Id <= 10 {
+			t.Errorf("expected id after 10, got %d", usage.Id)
+		}
+	})
+}
+
+func TestInsertMachinesVendorIds(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		// 两个平台返回相同的机器ID
+		if err := InsertMachinesIfNotExists([]Machine{{Vendor: LegacyVendor, VendorId: 1, Name: "A1", ShopId: "shop"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := InsertMachinesIfNotExists([]Machine{{Vendor: "campus", VendorId: 1, Name: "B1", ShopId: "campus"}}); err != nil {
+			t.Fatal(err)
+		}
+		// 同一平台重复插入时跳过
+		if err := InsertMachinesIfNotExists([]Machine{{Vendor: "campus", VendorId: 1, Name: "B1 again", ShopId: "campus"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		machines, err := GetAllMachines()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(machines) != 2 || machines[0].Id == machines[1].Id {
+			t.Fatalf("expected 2 machines with distinct ids, got %+v", machines)
+		}
+		campus, err := GetMachinesByShopID("campus")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(campus) != 1 || campus[0].Name != "B1" || campus[0].VendorId != 1 {
+			t.Fatalf("expected campus machine B1, got %+v", campus)
+		}
+
+		// 使用记录按各自的机器ID统计
+		base := time.Now().Unix()
+		if err := CreateUsage(&Usage{MachineId: campus[0].Id, StartTime: base, EndTime: base + 600}); err != nil {
+			t.Fatal(err)
+		}
+		day := UsageDay(base)
+		for _, m := range machines {
+			got, err := GetMachinesWithUsageCount(m.ShopId, day, day)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := 0
+			if m.Id == campus[0].Id {
+				expected = 1
+			}
+			if len(got) != 1 || got[0].UsageCount != expected {
+				t.Errorf("shop %s: expected usage count %d, got %+v", m.ShopId, expected, got)
+			}
+		}
+	})
+}
+
+func TestMigrations(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		states, current, err := MigrationStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current != states[len(states)-1].Version {
+			t.Fatalf("expected latest version %d, got %d", states[len(states)-1].Version, current)
+		}
+
+		// 回滚前插入的机器在重新迁移后归入 qiekj
+		if err := db.Create(&Machine{Id: 7, Vendor: LegacyVendor, VendorId: 7, Name: "7号"}).Error; err != nil {
+			t.Fatal(err)
+		}
+		done, err := MigrateDown(4)
+		if err != nil || len(done) != 4 || done[3].Version != 2 {
+			t.Fatalf("migrate down: done=%+v err=%v", done, err)
+		}
+		if !db.Migrator().HasIndex("usages", "idx_usages_machine_id") || db.Migrator().HasTable("usage_daily") || db.Migrator().HasTable("app_meta") ||
+			db.Migrator().HasColumn(&Machine{}, "vendor") {
+			t.Error("expected schema of version 1 after rollback")
+		}
+		if done, err = MigrateUp(0); err != nil || len(done) != 4 {
+			t.Fatalf("migrate up: done=%d err=%v", len(done), err)
+		}
+		if !db.Migrator().HasIndex("usages", "idx_usages_machine_time") || !db.Migrator().HasTable("usage_daily") || !db.Migrator().HasTable("app_meta") ||
+			!db.Migrator().HasIndex(&Machine{}, "idx_machines_vendor") {
+			t.Error("expected latest schema after migrate up")
+		}
+		if machine, err := GetMachineByID(7); err != nil || machine.Vendor != LegacyVendor || machine.VendorId != 7 {
+			t.Errorf("expected existing machine to be backfilled as qiekj, got %+v err=%v", machine, err)
+		}
+
+		// 数据库版本高于程序支持的版本时拒绝启动
+		newer := &schemaMigration{Version: 9999, Name: "from_the_future"}
+		if err := db.Create(newer).Error; err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Delete(newer) })
+		if _, err := MigrateUp(0); !errors.Is(err, ErrSchemaTooNew) {
+			t.Errorf("expected ErrSchemaTooNew, got %v", err)
+		}
+	})
+}
+
+func TestSplitStatements(t *testing.T) {
+	stmts := splitStatements("-- comment\nCREATE TABLE a (\n  id integer\n);\n\nCREATE INDEX b ON a(id);\nDROP INDEX c")
+	if len(stmts) != 3 || stmts[0] != "CREATE TABLE a (\n  id integer\n);" || stmts[2] != "DROP INDEX c" {
+		t.Errorf("unexpected statements: %q", stmts)
+	}
+}
+
+func TestUsageRollups(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		base := time.Date(2025, 3, 10, 8, 0, 0, 0, time.Local).Unix()
+		nextDay := time.Date(2025, 3, 11, 0, 0, 0, 0, time.Local).Unix()
+		for _, usage := range []Usage{
+			{MachineId: 1, StartTime: base + 60, EndTime: base + 60 + 2400},
+			{MachineId: 1, StartTime: base + 1800, EndTime: base + 1800 + 3000},
+			{MachineId: 1, StartTime: base + 7200, EndTime: base + 7200 + 1800},
+			{MachineId: 1, StartTime: base + 86400, EndTime: base + 86400 + 1200},
+			{MachineId: 2, StartTime: base, EndTime: base + 600},
+			{MachineId: 2, StartTime: nextDay - 1200, EndTime: nextDay + 1200},
+		} {
+			if err := CreateUsage(&usage); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		check := func(stage string) {
+			t.Helper()
+			rows, err := GetDailyUsages(1, UsageDay(base), UsageDay(base+86400))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 2 || rows[0].UsageCount != 3 || rows[0].TotalDuration != 7200 || rows[1].UsageCount != 1 {
+				t.Fatalf("%s: unexpected daily rollups %+v", stage, rows)
+			}
+			if rows[0].AvgDuration() != 2400 {
+				t.Errorf("%s: expected average 2400s, got %d", stage, rows[0].AvgDuration())
+			}
+			var hourly []UsageHourly
+			if err := db.Where("machine_id = ?", 1).Order("hour_start").Find(&hourly).Error; err != nil {
+				t.Fatal(err)
+			}
+			// 跨小时的使用时长拆分到后续小时，次数只计入开始的小时
+			if len(hourly) != 4 || hourly[0].UsageCount != 2 || hourly[0].TotalDuration != 4200 ||
+				hourly[1].UsageCount != 0 || hourly[1].TotalDuration != 1200 {
+				t.Errorf("%s: unexpected hourly rollups %+v", stage, hourly)
+			}
+			var spill UsageHourly
+			if err := db.Where("machine_id = ? AND hour_start = ?", 2, nextDay).Take(&spill).Error; err != nil || spill.UsageCount != 0 || spill.TotalDuration != 1200 {
+				t.Errorf("%s: unexpected spilled hourly rollup %+v %v", stage, spill, err)
+			}
+		}
+		check("incremental")
+
+		total, err := GetHourlyUsages([]int64{1, 2}, base, base+3600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(total) != 1 || total[0].UsageCount != 3 || total[0].TotalDuration != 4800 {
+			t.Errorf("unexpected hourly totals %+v", total)
+		}
+
+		// 清空汇总后从使用记录重建
+		db.Where("1 = 1").Delete(&UsageDaily{})
+		db.Where("1 = 1").Delete(&UsageHourly{})
+		if missing, err := UsageRollupsMissing(); err != nil || !missing {
+			t.Fatalf("expected rollups to be missing: %t %v", missing, err)
+		}
+		if err := RebuildUsageRollups(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		check("rebuild")
+		if built, err := UsageRollupLocation(); err != nil || built != Location().String() {
+			t.Errorf("expected rollup location %s, got %q %v", Location(), built, err)
+		}
+
+		// 部分重建不重复累加
+		if err := RebuildUsageRollups(base+86400+600, 0); err != nil {
+			t.Fatal(err)
+		}
+		check("partial rebuild")
+	})
+}
+
+func TestRebuildUsageRollupsArchived(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		t.Cleanup(func() { SetLocation(nil) })
+		shanghai, _ := time.LoadLocation("Asia/Shanghai")
+		newYork, _ := time.LoadLocation("America/New_York")
+		SetLocation(shanghai)
+
+		recent := time.Date(2025, 3, 10, 8, 0, 0, 0, shanghai).Unix()
+		archived := &Usage{MachineId: 1, StartTime: recent - 40*86400, EndTime: recent - 40*86400 + 600}
+		for _, usage := range []*Usage{archived, {MachineId: 1, StartTime: recent, EndTime: recent + 600}} {
+			if err := CreateUsage(usage); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := RebuildUsageRollups(0, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		// 归档后改变时区，已归档日期的汇总无法重新计算
+		if err := db.Delete(archived).Error; err != nil {
+			t.Fatal(err)
+		}
+		SetLocation(newYork)
+		if err := RebuildUsageRollups(0, 0); !errors.Is(err, ErrRollupsArchived) {
+			t.Fatalf("expected ErrRollupsArchived, got %v", err)
+		}
+		if built, _ := UsageRollupLocation(); built != shanghai.String() {
+			t.Errorf("expected rollup location to stay %s, got %q", shanghai, built)
+		}
+		// 时区不变时照常重建
+		SetLocation(shanghai)
+		if err := RebuildUsageRollups(0, 0); err != nil {
+			t.Errorf("expected rebuild in the same zone to succeed, got %v", err)
+		}
+
+		// 没有已归档日期的汇总时可以改变时区
+		if err := db.Where("hour_start < ?", recent-86400).Delete(&UsageHourly{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		SetLocation(newYork)
+		if err := RebuildUsageRollups(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		if built, _ := UsageRollupLocation(); built != newYork.String() {
+			t.Errorf("expected rollup location %s, got %q", newYork, built)
+		}
+	})
+}
+
+func TestLocation(t *testing.T) {
+	t.Cleanup(func() { SetLocation(nil) })
+	ts := time.Date(2025, 3, 9, 17, 30, 0, 0, time.UTC).Unix()
+
+	// 同一时刻在不同时区属于不同日期，与进程时区无关
+	for _, c := range []struct {
+		tz, day string
+	}{
+		{"Asia/Shanghai", "2025-03-10"},
+		{"America/New_York", "2025-03-09"},
+		{"UTC", "2025-03-09"},
+	} {
+		loc, err := time.LoadLocation(c.tz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetLocation(loc)
+		if day := UsageDay(ts); day != c.day {
+			t.Errorf("%s: expected %s, got %s", c.tz, c.day, day)
+		}
+	}
+
+	// 夏令时开始当天只有23小时，零点和最近日期仍按本地日期计算
+	loc, _ := time.LoadLocation("America/New_York")
+	SetLocation(loc)
+	noon := time.Date(2025, 3, 10, 12, 0, 0, 0, loc)
+	if start := dayStart(noon.Unix()); start != time.Date(2025, 3, 10, 0, 0, 0, 0, loc).Unix() {
+		t.Errorf("unexpected day start %s", time.Unix(start, 0).In(loc))
+	}
+	if days := RecentDays(noon, 3); len(days) != 3 || days[0] != "2025-03-08" || days[2] != "2025-03-10" {
+		t.Errorf("unexpected recent days %v", days)
+	}
+	if s := FormatTime(noon.Unix()); s != "2025-03-10T12:00:00-04:00" {
+		t.Errorf("expected offset -04:00 after DST start, got %s", s)
+	}
+	if FormatTime(0) != "" {
+		t.Error("expected empty string for zero timestamp")
+	}
+
+	// 非整小时偏移的时区按当地整点划分小时
+	for _, tz := range []string{"Asia/Kolkata", "Australia/Adelaide", "America/New_York"} {
+		loc, _ := time.LoadLocation(tz)
+		SetLocation(loc)
+		local := time.Date(2025, 3, 9, 14, 50, 0, 0, loc)
+		if start := usageHourStart(local.Unix()); start != time.Date(2025, 3, 9, 14, 0, 0, 0, loc).Unix() {
+			t.Errorf("%s: unexpected hour start %s", tz, time.Unix(start, 0).In(loc))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package model
+
+// 排队状态
+const (
+	QueueStatusWaiting  = 0 // 等待中
+	QueueStatusNotified = 1 // 已通知，等待认领
+	QueueStatusClaimed  = 2 // 已认领
+	QueueStatusExpired  = 3 // 超时未认领或等待过久
+	QueueStatusLeft     = 4 // 主动退出
+)
+
+// QueueEntry 某洗衣房某类型机器的虚拟排队记录
+type QueueEntry struct {
+	Id         int64  `gorm:"primaryKey,autoIncrement"`
+	ShopId     string `gorm:"index:idx_queue_target"`
+	Type       string `gorm:"index:idx_queue_target"`
+	DeviceId   string `gorm:"index;size:64"`
+	Status     int    `gorm:"index"`
+	Webhook    string
+	CreatedAt  int64 `gorm:"autoCreateTime"`
+	NotifiedAt int64
+	ExpireAt   int64 // 已通知状态下的认领截止时间
+	UpdatedAt  int64 `gorm:"autoUpdateTime"`
+}
+
+// Active 排队记录是否仍在队列中
+func (e *QueueEntry) Active() bool {
+	return e.Status == QueueStatusWaiting || e.Status == QueueStatusNotified
+}
+
+// CreateQueueEntry 创建排队记录
+func CreateQueueEntry(entry *QueueEntry) error {
+	return db.Create(entry).Error
+}
+
+// UpdateQueueEntry 更新排队记录
+func UpdateQueueEntry(entry *QueueEntry) error {
+	return db.Save(entry).Error
+}
+
+// GetActiveQueueEntry 获取设备在指定队列中仍有效的排队记录
+func GetActiveQueueEntry(deviceId, shopId, machineType string) (*QueueEntry, error) {
+	var entry QueueEntry
+	err := db.Where("device_id = ? AND shop_id = ? AND type = ? AND status IN ?",
+		deviceId, shopId, machineType, []int{QueueStatusWaiting, QueueStatusNotified}).
+		First(&entry).Error
+	return &entry, err
+}
+
+// GetActiveQueueEntries 获取指定队列中仍有效的排队记录，按加入顺序排列
+func GetActiveQueueEntries(shopId, machineType string) ([]QueueEntry, error) {
+	var entries []QueueEntry
+	err := db.Where("shop_id = ? AND type = ? AND status IN ?",
+		shopId, machineType, []int{QueueStatusWaiting, QueueStatusNotified}).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// GetAllActiveQueueEntries 获取所有队列中仍有效的排队记录，按加入顺序排列
+func GetAllActiveQueueEntries() ([]QueueEntry, error) {
+	var entries []QueueEntry
+	err := db.Where("status IN ?", []int{QueueStatusWaiting, QueueStatusNotified}).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// GetDispatchQueueEntries 获取指定队列中参与分配机器的排队记录：
+// 等待中、已通知，以及宽限期内已认领（仍预留着空闲机器）的记录
+func GetDispatchQueueEntries(shopId, machineType string, now int64) ([]QueueEntry, error) {
+	var entries []QueueEntry
+	err := db.Where("shop_id = ? AND type = ?", shopId, machineType).
+		Where(db.Where("status IN ?", []int{QueueStatusWaiting, QueueStatusNotified}).
+			Or("status = ? AND expire_at > ?", QueueStatusClaimed, now)).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
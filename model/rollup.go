@@ -0,0 +1,278 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DayLayout 按天汇总的日期格式
+const DayLayout = "2006-01-02"
+
+const rollupBatchSize = 1000
+
+// rollupLocationMeta 记录完整重建使用汇总时所用时区的元数据名
+const rollupLocationMeta = "usage_rollup_location"
+
+// UsageDaily 每台机器每天的使用汇总，按使用开始时间所在的日期归类
+type UsageDaily struct {
+	MachineId     int64  `gorm:"primaryKey;autoIncrement:false"`
+	Day           string `gorm:"primaryKey;size:10"` // 日期，格式见 DayLayout
+	UsageCount    int64
+	TotalDuration int64 // 使用总时长，单位秒
+}
+
+func (UsageDaily) TableName() string {
+	return "usage_daily"
+}
+
+// AvgDuration 平均使用时长，单位秒
+func (u *UsageDaily) AvgDuration() int64 {
+	if u.UsageCount == 0 {
+		return 0
+	}
+	return u.TotalDuration / u.UsageCount
+}
+
+// UsageHourly 每台机器每小时的使用汇总，使用次数按开始时间所在的小时归类，
+// 使用时长按每小时实际占用的时间拆分，跨小时的使用在后续小时记为次数0
+type UsageHourly struct {
+	MachineId     int64 `gorm:"primaryKey;autoIncrement:false"`
+	HourStart     int64 `gorm:"primaryKey;autoIncrement:false"` // 洗衣房时区整点的时间戳
+	UsageCount    int64
+	TotalDuration int64 // 使用总时长，单位秒
+}
+
+func (UsageHourly) TableName() string {
+	return "usage_hourly"
+}
+
+// UsageDay 使用开始时间在洗衣房时区所在的日期
+func UsageDay(startTime int64) string {
+	return time.Unix(startTime, 0).In(Location()).Format(DayLayout)
+}
+
+// usageHourStart 使用开始时间在洗衣房时区所在小时的整点时间戳
+// 按当地偏移对齐，Asia/Kolkata 等非整小时偏移的时区也落在当地整点
+func usageHourStart(startTime int64) int64 {
+	_, offset := time.Unix(startTime, 0).In(Location()).Zone()
+	local := startTime + int64(offset)
+	return startTime - (local%3600+3600)%3600
+}
+
+// addUsageRollup 将一条使用记录累加到按天、按小时的汇总
+func addUsageRollup(tx *gorm.DB, usage *Usage) error {
+	increase := func(table string, count, duration int64) clause.Set {
+		return clause.Set{
+			{Column: clause.Column{Name: "usage_count"}, Value: gorm.Expr(table+".usage_count + ?", count)},
+			{Column: clause.Column{Name: "total_duration"}, Value: gorm.Expr(table+".total_duration + ?", duration)},
+		}
+	}
+	daily := &UsageDaily{MachineId: usage.MachineId, Day: UsageDay(usage.StartTime), UsageCount: 1, TotalDuration: usage.Duration()}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "machine_id"}, {Name: "day"}},
+		DoUpdates: increase(daily.TableName(), 1, usage.Duration()),
+	}).Create(daily).Error
+	if err != nil {
+		return err
+	}
+	usageHours(usage, func(hourStart, count, duration int64) {
+		if err != nil {
+			return
+		}
+		hourly := &UsageHourly{MachineId: usage.MachineId, HourStart: hourStart, UsageCount: count, TotalDuration: duration}
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "machine_id"}, {Name: "hour_start"}},
+			DoUpdates: increase(hourly.TableName(), count, duration),
+		}).Create(hourly).Error
+	})
+	return err
+}
+
+// usageHours 将使用记录拆分到占用的各个小时，次数只计入开始时间所在的小时，时长按每小时实际占用的秒数拆分
+func usageHours(usage *Usage, fn func(hourStart, count, duration int64)) {
+	start, hour, count := usage.StartTime, usageHourStart(usage.StartTime), int64(1)
+	for {
+		next := usageHourStart(hour + 3600)
+		if usage.EndTime <= next {
+			fn(hour, count, usage.EndTime-start)
+			return
+		}
+		fn(hour, count, next-start)
+		start, hour, count = next, next, 0
+	}
+}
+
+// GetDailyUsages 获取机器在日期范围内（含首尾）的每日汇总，按日期排列，没有使用的日期不返回
+func GetDailyUsages(machineId int64, startDay, endDay string) ([]UsageDaily, error) {
+	var rows []UsageDaily
+	err := db.Where("machine_id = ? AND day >= ? AND day <= ?", machineId, startDay, endDay).
+		Order("day").Find(&rows).Error
+	return rows, err
+}
+
+// GetHourlyUsages 获取多台机器在 [from, to) 内每小时的合计使用汇总，按小时排列，没有使用的小时不返回
+// 返回的 MachineId 为0
+func GetHourlyUsages(machineIds []int64, from, to int64) ([]UsageHourly, error) {
+	var rows []UsageHourly
+	if len(machineIds) == 0 {
+		return rows, nil
+	}
+	err := db.Model(&UsageHourly{}).
+		Select("hour_start, SUM(usage_count) AS usage_count, SUM(total_duration) AS total_duration").
+		Where("machine_id IN ? AND hour_start >= ? AND hour_start < ?", machineIds, from, to).
+		Group("hour_start").Order("hour_start").Scan(&rows).Error
+	return rows, err
+}
+
+// dayStart 时间戳在洗衣房时区所在日期的零点
+func dayStart(ts int64) int64 {
+	t := time.Unix(ts, 0).In(Location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Unix()
+}
+
+// ErrRollupsArchived 时区变化后需要完整重建，但部分日期的使用记录已归档，这些日期的汇总无法按新时区重新计算
+var ErrRollupsArchived = errors.New("已归档日期的使用汇总无法按新时区重新计算，请先用 import --in <归档文件> 导入归档的使用记录，或改回原时区")
+
+// RebuildUsageRollups 根据使用记录重新计算 [since, until) 内的使用汇总，until 为0时不限结束时间
+// 范围会扩展到整天，保证每天的汇总完整；早于最早一条使用记录的汇总（如已归档的数据）保持不变
+// since 和 until 都为0时为完整重建，同时记录当前时区，见 UsageRollupLocation；
+// 时区与上次完整重建时不同且存在已归档日期的汇总时返回 ErrRollupsArchived
+func RebuildUsageRollups(since, until int64) error {
+	full := since <= 0 && until <= 0
+	var earliest sql.NullInt64
+	if err := db.Model(&Usage{}).Select("MIN(start_time)").Scan(&earliest).Error; err != nil {
+		return err
+	}
+	if full {
+		if err := checkArchivedRollups(earliest); err != nil {
+			return err
+		}
+	}
+	if !earliest.Valid {
+		if full {
+			return setMeta(db, rollupLocationMeta, Location().String())
+		}
+		return nil
+	}
+	since = dayStart(max(since, earliest.Int64))
+	if until > 0 {
+		until = time.Unix(dayStart(until-1), 0).In(Location()).AddDate(0, 0, 1).Unix()
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if full {
+			if err := setMeta(tx, rollupLocationMeta, Location().String()); err != nil {
+				return err
+			}
+		}
+		dailyQuery := tx.Where("day >= ?", UsageDay(since))
+		hourlyQuery := tx.Where("hour_start >= ?", since)
+		// 跨小时的使用会拆分到后续小时，开始时间早于 since 但结束时间在之后的使用也要重新拆分
+		usageQuery := tx.Where("end_time > ?", since)
+		if until > 0 {
+			dailyQuery = dailyQuery.Where("day < ?", UsageDay(until))
+			hourlyQuery = hourlyQuery.Where("hour_start < ?", until)
+			usageQuery = usageQuery.Where("start_time < ?", until)
+		}
+		if err := dailyQuery.Delete(&UsageDaily{}).Error; err != nil {
+			return err
+		}
+		if err := hourlyQuery.Delete(&UsageHourly{}).Error; err != nil {
+			return err
+		}
+
+		daily := make(map[UsageDaily]*UsageDaily)
+		hourly := make(map[UsageHourly]*UsageHourly)
+		var batch []Usage
+		err := usageQuery.Order("id").FindInBatches(&batch, rollupBatchSize, func(*gorm.DB, int) error {
+			for _, u := range batch {
+				if u.StartTime >= since {
+					d := UsageDaily{MachineId: u.MachineId, Day: UsageDay(u.StartTime)}
+					if daily[d] == nil {
+						daily[d] = &UsageDaily{MachineId: d.MachineId, Day: d.Day}
+					}
+					daily[d].UsageCount++
+					daily[d].TotalDuration += u.Duration()
+				}
+
+				usageHours(&u, func(hourStart, count, duration int64) {
+					if hourStart < since || (until > 0 && hourStart >= until) {
+						return
+					}
+					h := UsageHourly{MachineId: u.MachineId, HourStart: hourStart}
+					if hourly[h] == nil {
+						hourly[h] = &UsageHourly{MachineId: h.MachineId, HourStart: h.HourStart}
+					}
+					hourly[h].UsageCount += count
+					hourly[h].TotalDuration += duration
+				})
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		dailyRows := make([]*UsageDaily, 0, len(daily))
+		for _, row := range daily {
+			dailyRows = append(dailyRows, row)
+		}
+		hourlyRows := make([]*UsageHourly, 0, len(hourly))
+		for _, row := range hourly {
+			hourlyRows = append(hourlyRows, row)
+		}
+		if len(dailyRows) > 0 {
+			if err := tx.CreateInBatches(dailyRows, rollupBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(hourlyRows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(hourlyRows, rollupBatchSize).Error
+	})
+}
+
+// checkArchivedRollups 时区与上次完整重建时不同时，检查是否存在早于最早一条使用记录的汇总
+// 使用记录所在小时的起点晚于其开始时间前一小时，按小时汇总不受时区影响地判断
+func checkArchivedRollups(earliest sql.NullInt64) error {
+	built, err := getMeta(db, rollupLocationMeta)
+	if err != nil || built == "" || built == Location().String() {
+		return err
+	}
+	query := db.Model(&UsageHourly{})
+	if earliest.Valid {
+		query = query.Where("hour_start <= ?", earliest.Int64-3600)
+	}
+	var archived int64
+	if err := query.Limit(1).Count(&archived).Error; err != nil {
+		return err
+	}
+	if archived > 0 {
+		return fmt.Errorf("%w（汇总按时区 %s 生成，当前时区 %s）", ErrRollupsArchived, built, Location())
+	}
+	return nil
+}
+
+// UsageRollupsMissing 已有使用记录但汇总表为空（如刚执行完迁移）时返回 true
+func UsageRollupsMissing() (bool, error) {
+	var usages, rollups int64
+	if err := db.Model(&Usage{}).Limit(1).Count(&usages).Error; err != nil {
+		return false, err
+	}
+	if err := db.Model(&UsageDaily{}).Limit(1).Count(&rollups).Error; err != nil {
+		return false, err
+	}
+	return usages > 0 && rollups == 0, nil
+}
+
+// UsageRollupLocation 上次完整重建使用汇总时的时区名称，从未完整重建过时返回空字符串
+// 与当前时区不一致时，按天、按小时的汇总都按旧时区划分，需要完整重建
+func UsageRollupLocation() (string, error) {
+	return getMeta(db, rollupLocationMeta)
+}
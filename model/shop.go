@@ -0,0 +1,17 @@
+package model
+
+var shopNames = map[string]string{
+	"202401041041470000069996565184": "沙河雁北洗衣房",
+	"202401041044000000069996552384": "沙河雁南洗衣房",
+	"202302071714530000012067133598": "海淀西土城校区",
+}
+
+// GetShopName 获取洗衣房名称
+// 暂无获取洗衣房名称的接口，先硬编码
+func GetShopName(shopId string) string {
+	name, ok := shopNames[shopId]
+	if !ok {
+		return "未知洗衣房"
+	}
+	return name
+}
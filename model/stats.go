@@ -0,0 +1,33 @@
+package model
+
+// MachineStat 按洗衣房和状态统计的机器数
+type MachineStat struct {
+	ShopId string
+	Code   int
+	Count  int64
+}
+
+// CountMachinesByShopAndCode 按洗衣房和状态统计机器数
+func CountMachinesByShopAndCode() ([]MachineStat, error) {
+	var stats []MachineStat
+	err := db.Model(&Machine{}).
+		Select("shop_id, code, COUNT(*) AS count").
+		Group("shop_id, code").
+		Order("shop_id, code").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// CountUsagesSince 统计指定时间之后开始的使用记录数
+func CountUsagesSince(startTime int64) (int64, error) {
+	var count int64
+	err := db.Model(&Usage{}).Where("start_time >= ?", startTime).Count(&count).Error
+	return count, err
+}
+
+// CountActiveQueueEntries 统计仍在排队中的记录数
+func CountActiveQueueEntries() (int64, error) {
+	var count int64
+	err := db.Model(&QueueEntry{}).Where("status IN ?", []int{QueueStatusWaiting, QueueStatusNotified}).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,78 @@
+package model
+
+// MachineStore 机器数据访问接口
+type MachineStore interface {
+	GetMachinesByShopID(shopId string) ([]Machine, error)
+	GetMachinesByShopIDAndType(shopId, machineType string) ([]Machine, error)
+	GetMachinesWithUsageCount(shopId string, startDay, endDay string) ([]Machine, error)
+	// GetMachineByID 机器不存在时返回 gorm.ErrRecordNotFound
+	GetMachineByID(machineId int64) (*Machine, error)
+	GetAllMachines() ([]*Machine, error)
+	UpdateMachine(machine *Machine) error
+	InsertMachinesIfNotExists(machines []Machine) error
+	UpdateMachineLike(machineId int64, value int64) (int64, error)
+}
+
+// UsageStore 使用记录数据访问接口
+type UsageStore interface {
+	CreateUsage(usage *Usage) error
+	GetDailyUsages(machineId int64, startDay, endDay string) ([]UsageDaily, error)
+	GetHourlyUsages(machineIds []int64, from, to int64) ([]UsageHourly, error)
+	ExportUsages(shopId string, from, to int64, fn func(rows []UsageExportRow) error) error
+}
+
+// GormStore 基于 gorm 的数据访问实现，使用 InitDB 打开的数据库
+type GormStore struct{}
+
+var (
+	_ MachineStore = GormStore{}
+	_ UsageStore   = GormStore{}
+)
+
+func (GormStore) GetMachinesByShopID(shopId string) ([]Machine, error) {
+	return GetMachinesByShopID(shopId)
+}
+
+func (GormStore) GetMachinesByShopIDAndType(shopId, machineType string) ([]Machine, error) {
+	return GetMachinesByShopIDAndType(shopId, machineType)
+}
+
+func (GormStore) GetMachinesWithUsageCount(shopId string, startDay, endDay string) ([]Machine, error) {
+	return GetMachinesWithUsageCount(shopId, startDay, endDay)
+}
+
+func (GormStore) GetMachineByID(machineId int64) (*Machine, error) {
+	return GetMachineByID(machineId)
+}
+
+func (GormStore) GetAllMachines() ([]*Machine, error) {
+	return GetAllMachines()
+}
+
+func (GormStore) UpdateMachine(machine *Machine) error {
+	return UpdateMachine(machine)
+}
+
+func (GormStore) InsertMachinesIfNotExists(machines []Machine) error {
+	return InsertMachinesIfNotExists(machines)
+}
+
+func (GormStore) UpdateMachineLike(machineId int64, value int64) (int64, error) {
+	return UpdateMachineLike(machineId, value)
+}
+
+func (GormStore) CreateUsage(usage *Usage) error {
+	return CreateUsage(usage)
+}
+
+func (GormStore) GetDailyUsages(machineId int64, startDay, endDay string) ([]UsageDaily, error) {
+	return GetDailyUsages(machineId, startDay, endDay)
+}
+
+func (GormStore) GetHourlyUsages(machineIds []int64, from, to int64) ([]UsageHourly, error) {
+	return GetHourlyUsages(machineIds, from, to)
+}
+
+func (GormStore) ExportUsages(shopId string, from, to int64, fn func(rows []UsageExportRow) error) error {
+	return ExportUsages(shopId, from, to, fn)
+}
@@ -1,22 +1,25 @@
 package model
 
+import "gorm.io/gorm"
+
 type Usage struct {
 	Id        int64 `gorm:"primaryKey,autoIncrement"`
-	MachineId int64 `gorm:"index"`
-	StartTime int64 `gorm:"index"`
+	MachineId int64 `gorm:"index:idx_usages_machine_time"`
+	StartTime int64 `gorm:"index;index:idx_usages_machine_time"`
 	EndTime   int64
 }
 
-// CreateUsage 创建新使用记录
-func CreateUsage(usage *Usage) error {
-	return db.Create(usage).Error
+// Duration 使用时长，单位秒
+func (u *Usage) Duration() int64 {
+	return u.EndTime - u.StartTime
 }
 
-// CountUsagesByMachineIDAndTimeRange 统计指定机器在指定时间范围的使用次数
-func CountUsagesByMachineIDAndTimeRange(machineId, startTime, endTime int64) (int64, error) {
-	var count int64
-	err := db.Model(&Usage{}).
-		Where("machine_id = ? AND start_time >= ? AND start_time <= ?", machineId, startTime, endTime).
-		Count(&count).Error
-	return count, err
+// CreateUsage 创建新使用记录，并同步累加按天、按小时的使用汇总
+func CreateUsage(usage *Usage) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+		return addUsageRollup(tx, usage)
+	})
 }
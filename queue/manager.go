@@ -0,0 +1,376 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+	"washwise/config"
+	"washwise/event"
+	"washwise/model"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownQueue = errors.New("该洗衣房没有此类型的机器")
+	ErrNotInQueue   = errors.New("不在队列中")
+	ErrNotYourTurn  = errors.New("尚未轮到")
+)
+
+// Manager 虚拟排队管理器，队列状态保存在数据库中，重启后继续生效
+type Manager struct {
+	ctx      context.Context // 停止时取消，中断未发送完的 webhook 通知
+	cancel   context.CancelFunc
+	machines model.MachineStore
+
+	mu       sync.Mutex // 串行化所有队列变更
+	trigger  chan struct{}
+	ticker   *time.Ticker
+	done     chan struct{} // 停止时关闭，结束检查任务
+	stopOnce sync.Once
+	stopped  bool           // 停止后不再发送 webhook 通知，受 mu 保护
+	webhooks sync.WaitGroup // 发送中的 webhook 通知
+
+	// 上次推送的排队位置，避免每次检查都推送
+	lastPosition map[int64]int
+}
+
+// Position 排队者在队列中的位置
+type Position struct {
+	Entry    *model.QueueEntry
+	Position int   // 前方等待人数+1，已通知时为0
+	Eta      int64 // 预计轮到的剩余秒数，无法预测时为-1
+}
+
+var m *Manager
+
+// InitManager 初始化排队管理器，通过 machines 读取各队列的机器状态
+func InitManager(machines model.MachineStore) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m = &Manager{
+		ctx:          ctx,
+		cancel:       cancel,
+		machines:     machines,
+		trigger:      make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		lastPosition: make(map[int64]int),
+	}
+	return m
+}
+
+// GetManager 获取排队管理器实例
+func GetManager() *Manager {
+	return m
+}
+
+// Start 启动排队检查任务，机器状态变化时立即检查，并定期处理过期记录
+func (m *Manager) Start() {
+	sub := event.Subscribe(event.Filter{All: true})
+	ticker := time.NewTicker(config.GetQueueCheckInterval())
+	m.ticker = ticker
+
+	go func() {
+		defer ticker.Stop()
+		defer sub.Close()
+		m.check()
+		for {
+			select {
+			case <-m.done:
+				return
+			case e, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if e.Type == event.TypeMachineStatus {
+					m.Trigger()
+				}
+			case <-m.trigger:
+				m.check()
+			case <-ticker.C:
+				m.check()
+			}
+		}
+	}()
+	log.Info("排队检查任务已启动")
+}
+
+// ApplyConfig 应用重新加载的配置，调整检查周期；宽限期和最长等待时间在下次检查时生效
+func (m *Manager) ApplyConfig(old, new *config.Config) {
+	if m.ticker != nil && old.Queue.CheckInterval != new.Queue.CheckInterval {
+		m.ticker.Reset(new.Queue.CheckInterval.Duration())
+		log.WithField("check_interval", new.Queue.CheckInterval).Info("排队检查周期已调整")
+	}
+	m.Trigger()
+}
+
+// Stop 停止排队检查任务，等待进行中的检查和 webhook 通知完成，最多等待 cron.shutdown_timeout
+// 超时后取消未发送完的通知并返回错误；可重复调用
+func (m *Manager) Stop() error {
+	var err error
+	m.stopOnce.Do(func() {
+		close(m.done)
+		m.mu.Lock()
+		m.stopped = true
+		m.mu.Unlock()
+
+		finished := make(chan struct{})
+		go func() {
+			m.webhooks.Wait()
+			close(finished)
+		}()
+		timer := time.NewTimer(config.Get().Cron.ShutdownTimeout.Duration())
+		defer timer.Stop()
+		select {
+		case <-finished:
+			log.Info("排队检查任务已停止")
+		case <-timer.C:
+			err = errors.New("等待 webhook 通知发送超时，已取消未完成的通知")
+		}
+		m.cancel()
+	})
+	return err
+}
+
+// Trigger 请求尽快检查一次队列
+func (m *Manager) Trigger() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Join 加入队列，已在队列中时返回当前位置
+func (m *Manager) Join(deviceId, shopId, machineType, webhook string) (*Position, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	machines, err := m.machines.GetMachinesByShopIDAndType(shopId, machineType)
+	if err != nil {
+		return nil, err
+	}
+	if len(machines) == 0 {
+		return nil, ErrUnknownQueue
+	}
+
+	entry, err := model.GetActiveQueueEntry(deviceId, shopId, machineType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		entry = &model.QueueEntry{
+			ShopId:   shopId,
+			Type:     machineType,
+			DeviceId: deviceId,
+			Status:   model.QueueStatusWaiting,
+			Webhook:  webhook,
+		}
+		if err := model.CreateQueueEntry(entry); err != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{
+			"shopId": shopId,
+			"type":   machineType,
+			"entry":  entry.Id,
+		}).Info("加入排队")
+	} else if err != nil {
+		return nil, err
+	} else if webhook != "" && webhook != entry.Webhook {
+		entry.Webhook = webhook
+		if err := model.UpdateQueueEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	// 立即分配，空闲机器足够时加入即被通知
+	if err := m.dispatch(shopId, machineType, make(map[int64]bool)); err != nil {
+		return nil, err
+	}
+	entry, err = model.GetActiveQueueEntry(deviceId, shopId, machineType)
+	if err != nil {
+		return nil, err
+	}
+	return m.position(entry)
+}
+
+// Status 获取设备在队列中的位置
+func (m *Manager) Status(deviceId, shopId, machineType string) (*Position, error) {
+	entry, err := model.GetActiveQueueEntry(deviceId, shopId, machineType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotInQueue
+	} else if err != nil {
+		return nil, err
+	}
+	return m.position(entry)
+}
+
+// Leave 退出队列
+func (m *Manager) Leave(deviceId, shopId, machineType string) error {
+	return m.finish(deviceId, shopId, machineType, model.QueueStatusLeft)
+}
+
+// Claim 轮到后认领机器，认领的机器在宽限期内保持预留
+func (m *Manager) Claim(deviceId, shopId, machineType string) error {
+	return m.finish(deviceId, shopId, machineType, model.QueueStatusClaimed)
+}
+
+func (m *Manager) finish(deviceId, shopId, machineType string, status int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, err := model.GetActiveQueueEntry(deviceId, shopId, machineType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotInQueue
+	} else if err != nil {
+		return err
+	}
+	if status == model.QueueStatusClaimed && entry.Status != model.QueueStatusNotified {
+		return ErrNotYourTurn
+	}
+
+	entry.Status = status
+	if err := model.UpdateQueueEntry(entry); err != nil {
+		return err
+	}
+	m.Trigger()
+	return nil
+}
+
+// check 检查所有队列：处理过期记录，为空闲机器通知排队者，推送位置变化
+func (m *Manager) check() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := model.GetAllActiveQueueEntries()
+	if err != nil {
+		log.WithError(err).Error("获取排队记录失败")
+		return
+	}
+
+	type target struct{ shopId, machineType string }
+	targets := make([]target, 0)
+	for _, entry := range entries {
+		t := target{entry.ShopId, entry.Type}
+		if !slices.Contains(targets, t) {
+			targets = append(targets, t)
+		}
+	}
+
+	active := make(map[int64]bool)
+	for _, t := range targets {
+		if err := m.dispatch(t.shopId, t.machineType, active); err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"shopId": t.shopId,
+				"type":   t.machineType,
+			}).Error("处理排队失败")
+		}
+	}
+
+	for id := range m.lastPosition {
+		if !active[id] {
+			delete(m.lastPosition, id)
+		}
+	}
+}
+
+// dispatch 处理单个队列
+func (m *Manager) dispatch(shopId, machineType string, active map[int64]bool) error {
+	now := time.Now()
+	snap, err := loadSnapshot(m.machines, shopId, machineType, now)
+	if err != nil {
+		return err
+	}
+
+	maxWait := int64(config.GetQueueMaxWait().Seconds())
+	for i := range snap.entries {
+		entry := &snap.entries[i]
+		switch {
+		case entry.Status == model.QueueStatusNotified && entry.ExpireAt <= now.Unix():
+			// 超时未认领，让给下一位
+			entry.Status = model.QueueStatusExpired
+		case entry.Status == model.QueueStatusWaiting && maxWait > 0 && entry.CreatedAt+maxWait <= now.Unix():
+			entry.Status = model.QueueStatusExpired
+		default:
+			continue
+		}
+		if err := model.UpdateQueueEntry(entry); err != nil {
+			return err
+		}
+		event.Publish(event.Event{
+			Type:     event.TypeQueueExpired,
+			ShopId:   shopId,
+			DeviceId: entry.DeviceId,
+			Data:     newEntryData(entry),
+		})
+		log.WithField("entry", entry.Id).Info("排队记录过期")
+	}
+	snap.refresh()
+
+	// 为未预留的空闲机器依次通知排队者
+	grace := int64(config.GetQueueGracePeriod().Seconds())
+	for _, entry := range snap.waiting() {
+		if snap.freeUnassigned() <= 0 {
+			break
+		}
+		entry.Status = model.QueueStatusNotified
+		entry.NotifiedAt = now.Unix()
+		entry.ExpireAt = now.Unix() + grace
+		if err := model.UpdateQueueEntry(entry); err != nil {
+			return err
+		}
+		snap.refresh()
+		m.notifyTurn(entry)
+	}
+
+	for _, entry := range snap.waiting() {
+		active[entry.Id] = true
+		position := snap.position(entry)
+		if m.lastPosition[entry.Id] == position {
+			continue
+		}
+		m.lastPosition[entry.Id] = position
+		event.Publish(event.Event{
+			Type:     event.TypeQueuePosition,
+			ShopId:   shopId,
+			DeviceId: entry.DeviceId,
+			Data:     newPositionData(entry, position, snap.eta(position)),
+		})
+	}
+	return nil
+}
+
+// notifyTurn 通知排队者轮到使用
+func (m *Manager) notifyTurn(entry *model.QueueEntry) {
+	log.WithFields(log.Fields{
+		"shopId": entry.ShopId,
+		"type":   entry.Type,
+		"entry":  entry.Id,
+	}).Info("通知排队者使用")
+
+	data := newEntryData(entry)
+	event.Publish(event.Event{
+		Type:     event.TypeQueueTurn,
+		ShopId:   entry.ShopId,
+		DeviceId: entry.DeviceId,
+		Data:     data,
+	})
+	if entry.Webhook != "" && !m.stopped {
+		m.webhooks.Add(1)
+		go func() {
+			defer m.webhooks.Done()
+			sendWebhook(m.ctx, entry.Webhook, event.TypeQueueTurn, data)
+		}()
+	}
+}
+
+func (m *Manager) position(entry *model.QueueEntry) (*Position, error) {
+	snap, err := loadSnapshot(m.machines, entry.ShopId, entry.Type, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	position := snap.position(entry)
+	return &Position{
+		Entry:    entry,
+		Position: position,
+		Eta:      snap.eta(position),
+	}, nil
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"testing"
+	"time"
+	"washwise/config"
+)
+
+func TestStopWaitsForWebhooks(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cron.ShutdownTimeout = config.Duration(time.Second)
+	config.Set(cfg)
+
+	m := InitManager(nil)
+	m.webhooks.Add(1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.webhooks.Done()
+	}()
+	if err := m.Stop(); err != nil {
+		t.Fatalf("expected stop to wait for webhook, got %v", err)
+	}
+	if !m.stopped {
+		t.Error("expected no new webhooks after stop")
+	}
+	// 重复停止不会 panic
+	if err := m.Stop(); err != nil {
+		t.Errorf("expected second stop to succeed, got %v", err)
+	}
+}
+
+func TestStopWebhookTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Cron.ShutdownTimeout = config.Duration(50 * time.Millisecond)
+	config.Set(cfg)
+
+	m := InitManager(nil)
+	m.webhooks.Add(1)
+	if err := m.Stop(); err == nil {
+		t.Error("expected timeout error")
+	}
+	if m.ctx.Err() == nil {
+		t.Error("expected pending webhooks to be canceled after timeout")
+	}
+}
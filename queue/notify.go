@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+	"washwise/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const webhookTimeout = 5 * time.Second
+
+var statusNames = map[int]string{
+	model.QueueStatusWaiting:  "waiting",
+	model.QueueStatusNotified: "notified",
+	model.QueueStatusClaimed:  "claimed",
+	model.QueueStatusExpired:  "expired",
+	model.QueueStatusLeft:     "left",
+}
+
+// StatusName 获取排队状态的名称
+func StatusName(status int) string {
+	return statusNames[status]
+}
+
+// EntryData 排队记录事件数据
+type EntryData struct {
+	EntryId         int64  `json:"entryId"`
+	ShopId          string `json:"shopId"`
+	Type            string `json:"type"`
+	Status          string `json:"status"`
+	ExpireAt        int64  `json:"expireAt,omitempty"`        // 认领截止时间
+	ExpireAtRfc3339 string `json:"expireAtRfc3339,omitempty"` // 同 expireAt，洗衣房时区的 RFC3339 格式
+}
+
+// PositionData 排队位置事件数据
+type PositionData struct {
+	EntryId  int64  `json:"entryId"`
+	ShopId   string `json:"shopId"`
+	Type     string `json:"type"`
+	Position int    `json:"position"`
+	Eta      int64  `json:"eta"` // 预计轮到的剩余秒数，-1 表示无法预测
+}
+
+func newEntryData(entry *model.QueueEntry) *EntryData {
+	return &EntryData{
+		EntryId:         entry.Id,
+		ShopId:          entry.ShopId,
+		Type:            entry.Type,
+		Status:          StatusName(entry.Status),
+		ExpireAt:        entry.ExpireAt,
+		ExpireAtRfc3339: model.FormatTime(entry.ExpireAt),
+	}
+}
+
+func newPositionData(entry *model.QueueEntry, position int, eta int64) *PositionData {
+	return &PositionData{
+		EntryId:  entry.Id,
+		ShopId:   entry.ShopId,
+		Type:     entry.Type,
+		Position: position,
+		Eta:      eta,
+	}
+}
+
+type webhookPayload struct {
+	Type        string `json:"type"`
+	Time        int64  `json:"time"`
+	TimeRfc3339 string `json:"timeRfc3339"` // 同 time，洗衣房时区的 RFC3339 格式
+	Data        any    `json:"data"`
+}
+
+var client = newWebhookClient(publicAddr)
+
+// sendWebhook 向排队者登记的地址推送事件，失败只记录日志
+func sendWebhook(ctx context.Context, url, eventType string, data any) {
+	// 配置的主机列表可能在加入排队后变更，推送前重新检查
+	if err := ValidateWebhook(url); err != nil {
+		log.WithError(err).WithField("url", url).Warn("拒绝推送 webhook")
+		return
+	}
+	now := time.Now().Unix()
+	body, err := json.Marshal(webhookPayload{Type: eventType, Time: now, TimeRfc3339: model.FormatTime(now), Data: data})
+	if err != nil {
+		log.WithError(err).Error("序列化 webhook 失败")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		log.WithError(err).WithField("url", url).Warn("创建 webhook 请求失败")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.WithError(err).WithField("url", url).Warn("推送 webhook 失败")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.WithField("url", url).Warnf("webhook 返回状态码 %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"slices"
+	"time"
+	"washwise/model"
+)
+
+// snapshot 单个队列在某一时刻的机器和排队状态
+type snapshot struct {
+	now      int64
+	entries  []model.QueueEntry
+	free     int     // 空闲机器数
+	reserved int     // 已通知或刚认领、预留着空闲机器的排队数
+	ends     []int64 // 使用中机器的预计剩余秒数，升序
+	useTime  int64   // 平均单次使用时间
+}
+
+func loadSnapshot(store model.MachineStore, shopId, machineType string, now time.Time) (*snapshot, error) {
+	machines, err := store.GetMachinesByShopIDAndType(shopId, machineType)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := model.GetDispatchQueueEntries(shopId, machineType, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &snapshot{now: now.Unix(), entries: entries}
+	for i := range machines {
+		machine := &machines[i]
+		s.useTime += machine.PredictUseTime()
+		switch machine.Code {
+		case model.MachineCodeAvailable:
+			s.free++
+		case model.MachineCodeInUse:
+			s.ends = append(s.ends, machine.PredictRemainTime())
+		}
+	}
+	if len(machines) > 0 {
+		s.useTime /= int64(len(machines))
+	}
+	slices.Sort(s.ends)
+	s.refresh()
+	return s, nil
+}
+
+// refresh 排队记录状态变化后重新统计预留数
+func (s *snapshot) refresh() {
+	s.reserved = 0
+	for i := range s.entries {
+		entry := &s.entries[i]
+		if (entry.Status == model.QueueStatusNotified || entry.Status == model.QueueStatusClaimed) && entry.ExpireAt > s.now {
+			s.reserved++
+		}
+	}
+}
+
+// freeUnassigned 尚未分配给排队者的空闲机器数
+func (s *snapshot) freeUnassigned() int {
+	return s.free - s.reserved
+}
+
+// waiting 按加入顺序返回等待中的排队记录
+func (s *snapshot) waiting() []*model.QueueEntry {
+	waiting := make([]*model.QueueEntry, 0, len(s.entries))
+	for i := range s.entries {
+		if s.entries[i].Status == model.QueueStatusWaiting {
+			waiting = append(waiting, &s.entries[i])
+		}
+	}
+	return waiting
+}
+
+// position 计算排队位置，非等待状态返回0
+func (s *snapshot) position(entry *model.QueueEntry) int {
+	if entry.Status != model.QueueStatusWaiting {
+		return 0
+	}
+	for i, waiting := range s.waiting() {
+		if waiting.Id == entry.Id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// eta 根据使用中机器的预计结束时间估算第 position 位轮到的剩余秒数；
+// 排在使用中机器数之后的，按平均使用时间顺延
+func (s *snapshot) eta(position int) int64 {
+	k := position - max(s.freeUnassigned(), 0) - 1
+	if position <= 0 || k < 0 {
+		return 0
+	}
+	if len(s.ends) == 0 {
+		return -1
+	}
+	n := len(s.ends)
+	return s.ends[k%n] + int64(k/n)*s.useTime
+}
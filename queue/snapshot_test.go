@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"testing"
+	"washwise/model"
+)
+
+func TestSnapshotEta(t *testing.T) {
+	s := &snapshot{
+		now: 1000,
+		entries: []model.QueueEntry{
+			{Id: 1, Status: model.QueueStatusNotified, ExpireAt: 2000},
+			{Id: 2, Status: model.QueueStatusWaiting},
+			{Id: 3, Status: model.QueueStatusWaiting},
+			{Id: 4, Status: model.QueueStatusWaiting},
+		},
+		free:    1,
+		ends:    []int64{300, 600},
+		useTime: 2400,
+	}
+	s.refresh()
+
+	if s.freeUnassigned() != 0 {
+		t.Fatalf("expected no unassigned machine, got %d", s.freeUnassigned())
+	}
+	for _, c := range []struct {
+		id       int64
+		position int
+		eta      int64
+	}{
+		{1, 0, 0},
+		{2, 1, 300},
+		{3, 2, 600},
+		{4, 3, 300 + 2400},
+	} {
+		position := s.position(&model.QueueEntry{Id: c.id, Status: s.entries[c.id-1].Status})
+		if position != c.position {
+			t.Errorf("entry %d: expected position %d, got %d", c.id, c.position, position)
+		}
+		if eta := s.eta(position); eta != c.eta {
+			t.Errorf("entry %d: expected eta %d, got %d", c.id, c.eta, eta)
+		}
+	}
+
+	s.ends = nil
+	if eta := s.eta(1); eta != -1 {
+		t.Errorf("expected unknown eta without machines in use, got %d", eta)
+	}
+}
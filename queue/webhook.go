@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"washwise/config"
+)
+
+// ErrInvalidWebhook webhook 地址不合法或不允许推送
+var ErrInvalidWebhook = errors.New("webhook 地址不合法")
+
+// publicAddr 判断地址是否可以作为 webhook 目标，拒绝回环、内网、链路本地（含云平台元数据地址）、未指定和组播地址
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// newWebhookClient 创建推送 webhook 的 HTTP 客户端
+// 在建立连接时检查解析后的 IP，域名解析到内网地址或推送过程中重新解析也无法绕过；不跟随重定向
+func newWebhookClient(allow func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !allow(addr) {
+				return fmt.Errorf("%w: 不允许连接 %s", ErrInvalidWebhook, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // 经过代理时无法检查目标地址
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// hostAllowed 判断主机名是否在 queue.webhook_hosts 中，列表为空时允许任意主机
+// 列表项支持 *.example.com 形式匹配子域名
+func hostAllowed(host string, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateWebhook 检查 webhook 地址：只允许 http/https、主机在 queue.webhook_hosts 中（已配置时），
+// IP 地址形式的主机不能是内网等地址；域名解析结果在推送建立连接时检查
+func ValidateWebhook(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidWebhook
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return fmt.Errorf("%w: 不允许推送到 %s", ErrInvalidWebhook, host)
+	}
+	if !hostAllowed(host, config.Get().Queue.WebhookHosts) {
+		return fmt.Errorf("%w: 主机 %s 不在 queue.webhook_hosts 中", ErrInvalidWebhook, host)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket 令牌桶，容量为 limit，每个 window 匀速补满
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  int
+	window time.Duration
+}
+
+// refill 按经过的时间补充令牌
+func (b *bucket) refill(now time.Time) {
+	rate := float64(b.limit) / b.window.Seconds()
+	b.tokens = min(float64(b.limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+}
+
+// Limiter 按 key 区分的令牌桶限流器
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New 创建限流器
+func New() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Limit 一个令牌桶的 key 和每个窗口的请求数
+type Limit struct {
+	Key   string
+	Limit int
+}
+
+// Allow 从 key 对应的令牌桶中取一个令牌，每个 window 最多 limit 个请求，允许短时突发
+// 令牌不足时返回 false 和需要等待的时长
+func (l *Limiter) Allow(key string, limit int, window time.Duration) (bool, time.Duration) {
+	return l.AllowAll(window, Limit{Key: key, Limit: limit})
+}
+
+// AllowAll 所有令牌桶都有令牌时各取一个，否则都不消耗，返回 false 和最长的等待时长
+// 避免一个桶拒绝请求时另一个桶仍被扣减
+func (l *Limiter) AllowAll(window time.Duration, limits ...Limit) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now, window)
+
+	buckets := make([]*bucket, len(limits))
+	var wait time.Duration
+	for i, limit := range limits {
+		b, ok := l.buckets[limit.Key]
+		if !ok {
+			b = &bucket{tokens: float64(limit.Limit), last: now}
+			l.buckets[limit.Key] = b
+		}
+		// 配置热重载后按新的预算计算
+		b.limit, b.window = limit.Limit, window
+		b.refill(now)
+		buckets[i] = b
+
+		if b.tokens < 1 {
+			rate := float64(limit.Limit) / window.Seconds()
+			wait = max(wait, time.Duration((1-b.tokens)/rate*float64(time.Second)))
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// sweep 每个窗口清理一次已补满的令牌桶，补满的桶与不存在的桶等价
+func (l *Limiter) sweep(now time.Time, window time.Duration) {
+	if now.Sub(l.lastSweep) < window {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit) {
+			delete(l.buckets, key)
+		}
+	}
+}
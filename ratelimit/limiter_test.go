@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+	"washwise/config"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLimiter(t *testing.T) {
+	l := New()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("ip:1", 3, time.Minute); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	ok, wait := l.Allow("ip:1", 3, time.Minute)
+	if ok || wait != 20*time.Second {
+		t.Fatalf("expected denial with 20s wait, got %t %s", ok, wait)
+	}
+	if ok, _ := l.Allow("ip:2", 3, time.Minute); !ok {
+		t.Error("other keys should not be affected")
+	}
+
+	// 每20秒补充一个令牌
+	now = now.Add(20 * time.Second)
+	if ok, _ := l.Allow("ip:1", 3, time.Minute); !ok {
+		t.Error("expected a refilled token")
+	}
+	if ok, _ := l.Allow("ip:1", 3, time.Minute); ok {
+		t.Error("expected denial after using the refilled token")
+	}
+
+	// 补满的令牌桶被清理
+	now = now.Add(2 * time.Minute)
+	l.Allow("ip:3", 3, time.Minute)
+	if len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be swept, got %d", len(l.buckets))
+	}
+}
+
+func TestLimiterAllowAll(t *testing.T) {
+	l := New()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	device := Limit{Key: "device:a", Limit: 1}
+	ip := Limit{Key: "ip:1", Limit: 3}
+	if ok, _ := l.AllowAll(time.Minute, ip, device); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	// 设备预算用尽时拒绝，且不扣减 IP 预算
+	for i := 0; i < 5; i++ {
+		if ok, wait := l.AllowAll(time.Minute, ip, device); ok || wait != time.Minute {
+			t.Fatalf("expected denial with 1m wait, got %t %s", ok, wait)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.AllowAll(time.Minute, ip); !ok {
+			t.Errorf("request %d: expected ip budget to be untouched by denied requests", i)
+		}
+	}
+	if ok, _ := l.AllowAll(time.Minute, ip); ok {
+		t.Error("expected ip budget to be used up")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.Read = config.RateBudget{IP: 100, Device: 2}
+	cfg.RateLimit.Write = config.RateBudget{IP: 1}
+	config.Set(cfg)
+
+	app := fiber.New()
+	app.Use(Middleware(New()))
+	app.Get("/machines", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/machine/:id/like", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	request := func(target, deviceId string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		if deviceId != "" {
+			req.Header.Set(util.HeaderDeviceId, deviceId)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderRetryAfter)
+	}
+
+	// 设备预算独立计数
+	for _, deviceId := range []string{"a", "a", "b"} {
+		if status, _ := request("/machines", deviceId); status != fiber.StatusOK {
+			t.Fatalf("device %s: expected 200, got %d", deviceId, status)
+		}
+	}
+	status, retryAfter := request("/machines", "a")
+	if status != fiber.StatusTooManyRequests || retryAfter != "30" {
+		t.Errorf("expected 429 with Retry-After 30, got %d %q", status, retryAfter)
+	}
+
+	// 点赞属于写接口，使用写预算
+	if status, _ := request("/machine/1/like", ""); status != fiber.StatusOK {
+		t.Errorf("expected first like to pass, got %d", status)
+	}
+	if status, _ := request("/machine/1/like", ""); status != fiber.StatusTooManyRequests {
+		t.Errorf("expected second like to be limited, got %d", status)
+	}
+	if status, _ := request("/machines", ""); status != fiber.StatusOK {
+		t.Errorf("expected read budget to be separate, got %d", status)
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"washwise/config"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 接口类别，读写接口使用不同的预算
+const (
+	classRead  = "read"
+	classWrite = "write"
+)
+
+// writeSuffixes 使用 GET 方法但会修改数据的接口
+var writeSuffixes = []string{"/like", "/dislike"}
+
+// classify 判断请求属于读接口还是写接口
+func classify(c *fiber.Ctx) string {
+	if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		return classWrite
+	}
+	for _, suffix := range writeSuffixes {
+		if strings.HasSuffix(c.Path(), suffix) {
+			return classWrite
+		}
+	}
+	return classRead
+}
+
+// Middleware 按客户端 IP 和设备标识限流，超出预算时返回 429 错误并带 Retry-After 头
+// 预算每次请求时从当前配置读取，热重载后立即生效
+func Middleware(l *Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := config.Get().RateLimit
+		if !cfg.Enabled || c.Method() == fiber.MethodOptions {
+			return c.Next()
+		}
+
+		class := classify(c)
+		budget := cfg.Read
+		if class == classWrite {
+			budget = cfg.Write
+		}
+
+		// IP 和设备的预算都充足时才同时扣减，被拒绝的请求不消耗任何预算
+		var limits []Limit
+		for _, limit := range []struct {
+			scope string
+			key   string
+			limit int
+		}{
+			{"ip", util.ClientIP(c), budget.IP},
+			{"device", util.DeviceId(c), budget.Device},
+		} {
+			if limit.key != "" && limit.limit > 0 {
+				limits = append(limits, Limit{Key: class + ":" + limit.scope + ":" + limit.key, Limit: limit.limit})
+			}
+		}
+		allowed, wait := l.AllowAll(cfg.Window.Duration(), limits...)
+		if allowed {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests, "too many requests")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"washwise/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// runRollup 根据使用记录重建按天、按小时的使用汇总
+func runRollup(configPath string, args []string) error {
+	fs := newFlagSet("rollup", &configPath)
+	since := fs.String("since", "", "只重建该日期（YYYY-MM-DD）及之后的汇总，默认重建所有仍保留使用记录的日期")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `用法: rollup [--since YYYY-MM-DD]
+
+根据仍保留的使用记录重建使用汇总，已归档日期的汇总保持不变。
+时区变化后需要完整重建，此时如果存在已归档日期的汇总会拒绝重建，
+需先用 import --in <归档文件> 导入归档的使用记录，或改回原时区。
+
+参数:
+`)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	// 日期按配置的洗衣房时区解析，需要先加载配置
+	if _, err := setup(configPath); err != nil {
+		return err
+	}
+	var sinceTime int64
+	if *since != "" {
+		t, err := time.ParseInLocation(model.DayLayout, *since, model.Location())
+		if err != nil {
+			return fmt.Errorf("--since 格式错误: %w", err)
+		}
+		sinceTime = t.Unix()
+	}
+	if sinceTime > 0 {
+		built, err := model.UsageRollupLocation()
+		if err != nil {
+			return err
+		}
+		if built != model.Location().String() {
+			return fmt.Errorf("使用汇总按时区 %q 生成，与当前时区 %s 不一致，需要去掉 --since 完整重建", built, model.Location())
+		}
+	}
+
+	begin := time.Now()
+	if err := model.RebuildUsageRollups(sinceTime, 0); err != nil {
+		return err
+	}
+	fmt.Printf("使用汇总重建完成，耗时 %s\n", time.Since(begin).Round(time.Millisecond))
+	return nil
+}
+
+// ensureUsageRollups 已有使用记录但汇总为空时（如升级后首次启动）回填汇总，
+// 汇总生成时的时区与当前配置不一致时完整重建
+func ensureUsageRollups() error {
+	missing, err := model.UsageRollupsMissing()
+	if err != nil {
+		return err
+	}
+	built, err := model.UsageRollupLocation()
+	if err != nil {
+		return err
+	}
+	switch {
+	case missing:
+		log.Info("使用汇总为空，开始根据使用记录回填...")
+	case built == "":
+		log.Info("使用汇总未记录生成时的时区，开始按当前时区重建...")
+	case built != model.Location().String():
+		log.WithFields(log.Fields{"from": built, "to": model.Location().String()}).Info("使用汇总的时区与当前配置不一致，开始重建...")
+	default:
+		return nil
+	}
+	if err := model.RebuildUsageRollups(0, 0); err != nil {
+		return fmt.Errorf("回填使用汇总失败: %w", err)
+	}
+	log.Info("使用汇总回填完成")
+	return nil
+}
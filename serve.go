@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"washwise/archive"
+	"washwise/config"
+	"washwise/cron"
+	"washwise/event"
+	"washwise/model"
+	"washwise/queue"
+	"washwise/server"
+	"washwise/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// configWatchInterval 检查配置文件是否修改的周期
+const configWatchInterval = 5 * time.Second
+
+// runServe 启动 HTTP 服务器、定时任务和虚拟排队，直到收到终止信号
+// 收到 SIGHUP 或配置文件修改时重新加载配置
+func runServe(configPath string, args []string) error {
+	fs := newFlagSet("serve", &configPath)
+	_ = fs.Parse(args)
+
+	// 使用配置初始化日志系统和数据库
+	fmt.Println("正在初始化日志系统...")
+	cfg, err := setup(configPath)
+	if err != nil {
+		return err
+	}
+	if err := ensureUsageRollups(); err != nil {
+		return err
+	}
+
+	// 初始化并启动定时任务
+	store := model.GormStore{}
+	taskManager := cron.InitTaskManager(store, store)
+	if cfg.Cron.Enabled {
+		taskManager.Start()
+	}
+
+	// 初始化并启动虚拟排队
+	queueManager := queue.InitManager(store)
+	queueManager.Start()
+
+	// 初始化并启动数据归档
+	archiver := archive.New()
+	archiver.Start()
+
+	// 初始化并启动HTTP服务器
+	log.Info("初始化 HTTP 服务器...")
+	srv := server.New(cfg, store, store)
+
+	// 在goroutine中启动服务器
+	go func() {
+		if err := srv.Start(); err != nil {
+			log.WithError(err).Fatal("HTTP 服务器启动失败")
+		}
+	}()
+
+	// 配置热重载
+	config.OnChange(func(old, new *config.Config) {
+		if old.Log != new.Log {
+			util.ApplyLogConfig(logConfig(new))
+			log.WithFields(log.Fields{
+				"level":  new.Log.Level,
+				"format": new.Log.Format,
+				"stdout": new.Log.Stdout,
+			}).Info("日志配置已调整")
+		}
+	})
+	config.OnChange(taskManager.ApplyConfig)
+	config.OnChange(queueManager.ApplyConfig)
+	config.OnChange(archiver.ApplyConfig)
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go config.Watch(watchCtx, configWatchInterval)
+
+	// 等待终止信号
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	// 服务启动信息直接打印到标准输出
+	fmt.Printf("  WashWise 服务已启动\n")
+	fmt.Printf("  按 Ctrl+C 退出，发送 SIGHUP 重新加载配置\n")
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		if err := config.Reload(); err != nil {
+			log.WithError(err).Error("重新加载配置失败，继续使用当前配置")
+		}
+	}
+
+	// 服务关闭信息直接打印到标准输出
+	fmt.Println("\n收到终止信号，正在关闭服务...")
+
+	// 按 HTTP 服务、定时任务、排队、事件总线、数据库的顺序关闭：
+	// 先停止接收请求，再等待产生事件的后台任务结束，保证关闭数据库时没有进行中的写入
+	if err := srv.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "关闭 HTTP 服务器失败: %v\n", err)
+	}
+	if err := taskManager.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "停止定时任务失败: %v\n", err)
+	}
+	if err := queueManager.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "停止排队检查失败: %v\n", err)
+	}
+	archiver.Stop()
+	event.Close()
+	if err := model.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "关闭数据库失败: %v\n", err)
+	}
+
+	fmt.Println("服务已完全关闭")
+	return nil
+}
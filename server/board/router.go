@@ -0,0 +1,18 @@
+package board
+
+import (
+	"washwise/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler 看板页面处理函数
+type Handler struct {
+	machines model.MachineStore
+}
+
+func RegisterRoutes(r fiber.Router, machines model.MachineStore) {
+	h := &Handler{machines: machines}
+
+	r.Get("/:shopId", h.GetBoard)
+}
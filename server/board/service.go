@@ -0,0 +1,110 @@
+package board
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"slices"
+	"strings"
+	"time"
+	"washwise/config"
+	"washwise/model"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var boardTmpl = template.Must(template.ParseFS(templates, "templates/board.html"))
+
+var statusTexts = map[int]string{
+	model.MachineCodeAvailable: "空闲",
+	model.MachineCodeOffline:   "离线",
+	model.MachineCodeInUse:     "使用中",
+}
+
+type boardData struct {
+	ShopId    string
+	ShopName  string
+	Available int
+	InUse     int
+	Groups    []*boardGroup
+	Now       int64
+}
+
+type boardGroup struct {
+	Type     string
+	Machines []*boardMachine
+}
+
+type boardMachine struct {
+	Id         int64
+	Name       string
+	Status     int
+	StatusText string
+	Msg        string
+	RemainTime int64
+}
+
+// GetBoard 渲染洗衣房状态看板，供洗衣房内的显示屏全屏展示
+func (h *Handler) GetBoard(c *fiber.Ctx) error {
+	shopId := c.Params("shopId")
+	if !slices.Contains(config.Get().Shops, shopId) {
+		return c.Status(fiber.StatusNotFound).SendString("未知洗衣房")
+	}
+
+	machines, err := h.machines.GetMachinesByShopID(shopId)
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal server error")
+	}
+
+	data := &boardData{
+		ShopId:   shopId,
+		ShopName: model.GetShopName(shopId),
+		Now:      time.Now().Unix(),
+	}
+
+	// 按类型分组，组内按名称排序
+	slices.SortFunc(machines, func(a, b model.Machine) int {
+		if a.Type != b.Type {
+			return strings.Compare(a.Type, b.Type)
+		}
+		if len(a.Name) != len(b.Name) {
+			return len(a.Name) - len(b.Name)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	for i := range machines {
+		machine := &machines[i]
+		if len(data.Groups) == 0 || data.Groups[len(data.Groups)-1].Type != machine.Type {
+			data.Groups = append(data.Groups, &boardGroup{Type: machine.Type})
+		}
+		group := data.Groups[len(data.Groups)-1]
+		group.Machines = append(group.Machines, &boardMachine{
+			Id:         machine.Id,
+			Name:       machine.Name,
+			Status:     machine.Code,
+			StatusText: statusTexts[machine.Code],
+			Msg:        machine.Msg,
+			RemainTime: machine.PredictRemainTime(),
+		})
+		switch machine.Code {
+		case model.MachineCodeAvailable:
+			data.Available++
+		case model.MachineCodeInUse:
+			data.InUse++
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := boardTmpl.Execute(&buf, data); err != nil {
+		util.RequestLogger(c).WithError(err).Error("render board failed")
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal server error")
+	}
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Type("html", "utf-8")
+	return c.Send(buf.Bytes())
+}
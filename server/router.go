@@ -1,8 +1,15 @@
 package server
 
 import (
+	"strings"
+	"time"
+	"washwise/config"
+	"washwise/model"
+	"washwise/ratelimit"
+	"washwise/server/board"
 	servicev1 "washwise/server/service_v1"
 	servicev2 "washwise/server/service_v2"
+	"washwise/util"
 
 	_ "washwise/docs"
 
@@ -11,14 +18,31 @@ import (
 	fiberSwagger "github.com/swaggo/fiber-swagger"
 )
 
-func RegisterServices(app *fiber.App) {
+func RegisterServices(app *fiber.App, machines model.MachineStore, usages model.UsageStore) {
 	// middleware
-	app.Use(cors.New(cors.ConfigDefault))
+	app.Use(cors.New(corsConfig(config.Get())))
+	app.Use(ratelimit.Middleware(ratelimit.New()))
 
 	// swagger
 	app.Get("/docs/*", fiberSwagger.WrapHandler)
 
 	// routes
-	servicev1.RegisterRoutes(app.Group("/api/v1"))
-	servicev2.RegisterRoutes(app.Group("/api/v2"))
+	servicev1.RegisterRoutes(app.Group("/api/v1"), machines, usages)
+	servicev2.RegisterRoutes(app.Group("/api/v2"), machines, usages)
+
+	// 洗衣房看板
+	board.RegisterRoutes(app.Group("/board"), machines)
+}
+
+// corsConfig 根据 server.security 配置生成跨域配置
+func corsConfig(cfg *config.Config) cors.Config {
+	security := cfg.Server.Security
+	return cors.Config{
+		AllowOrigins:     strings.Join(security.AllowedOrigins, ","),
+		AllowMethods:     strings.Join(security.AllowedMethods, ","),
+		AllowCredentials: security.AllowCredentials,
+		// 客户端需要读取的响应头：请求标识、缓存校验和限流等待时间
+		ExposeHeaders: strings.Join([]string{util.HeaderRequestId, fiber.HeaderETag, fiber.HeaderRetryAfter}, ","),
+		MaxAge:        int((12 * time.Hour).Seconds()),
+	}
 }
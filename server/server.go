@@ -1,9 +1,16 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"os"
 	"time"
 	"washwise/config"
+	"washwise/model"
+	servicev2 "washwise/server/service_v2"
 	"washwise/util"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,6 +21,7 @@ import (
 const (
 	readTimeout  = 30 * time.Second
 	writeTimeout = 30 * time.Second
+	socketMode   = 0660 // unix socket 文件权限
 )
 
 type Server struct {
@@ -21,19 +29,31 @@ type Server struct {
 	cfg *config.Config
 }
 
-// New 创建新的服务器实例
-func New(cfg *config.Config) *Server {
+// New 创建新的服务器实例，接口通过 machines 和 usages 读写机器与使用记录
+func New(cfg *config.Config, machines model.MachineStore, usages model.UsageStore) *Server {
+	security := cfg.Server.Security
+	proxies, err := util.ParseTrustedProxies(security.TrustedProxies)
+	if err != nil {
+		log.WithError(err).Error("解析可信代理失败，不信任任何代理")
+	}
+	util.SetTrustedProxies(proxies, security.ProxyHeader)
+
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
+		ErrorHandler: errorHandler,
+		// 只信任来自可信代理的 X-Forwarded-* 请求头，影响 c.IP()、c.Protocol() 和 c.Hostname()
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          security.TrustedProxies,
+		ProxyHeader:             security.ProxyHeader,
 	})
 
-	// 添加中间件
+	// 添加中间件，访问日志在最外层以记录 panic 恢复后的状态码
+	app.Use(util.AccessLog())
 	app.Use(recover.New())
-	app.Use(util.FiberLogger())
 
 	// 注册路由
-	RegisterServices(app)
+	RegisterServices(app, machines, usages)
 
 	return &Server{
 		app: app,
@@ -41,15 +61,83 @@ func New(cfg *config.Config) *Server {
 	}
 }
 
-// Start 启动服务器
+// errorHandler v2 接口返回统一响应，其余接口限流时返回 v1 格式的响应，其他错误保持 Fiber 默认行为
+func errorHandler(c *fiber.Ctx, err error) error {
+	if servicev2.IsV2Request(c) {
+		return servicev2.ErrorHandler(c, err)
+	}
+	var e *fiber.Error
+	if errors.As(err, &e) && e.Code == fiber.StatusTooManyRequests {
+		return util.TooManyRequests(c, e.Message)
+	}
+	return fiber.DefaultErrorHandler(c, err)
+}
+
+// listen 监听 unix socket 或 host:port
+func (s *Server) listen() (net.Listener, error) {
+	socket := s.cfg.Server.Socket
+	if socket == "" {
+		return net.Listen("tcp", fmt.Sprintf("%s:%d", s.cfg.Server.Host, s.cfg.Server.Port))
+	}
+
+	// 清理上次运行遗留的 socket 文件，不删除其他类型的文件
+	if info, err := os.Lstat(socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s 已存在且不是 socket 文件", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	// 反向代理进程需要与服务在同一用户组才能连接
+	if err := os.Chmod(socket, socketMode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Start 启动服务器，配置了证书时使用 HTTPS
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%d", s.cfg.Server.Host, s.cfg.Server.Port)
-	log.Infof("HTTP 服务器启动于 %s", addr)
-	return s.app.Listen(addr)
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	scheme := "HTTP"
+	if tlsCfg := s.cfg.Server.TLS; tlsCfg.CertFile != "" {
+		certs, err := newCertReloader(tlsCfg.CertFile, tlsCfg.KeyFile)
+		if err != nil {
+			_ = ln.Close()
+			return err
+		}
+		ln = tls.NewListener(ln, &tls.Config{
+			GetCertificate: certs.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+			NextProtos:     []string{"http/1.1"},
+		})
+		scheme = "HTTPS"
+	}
+
+	log.Infof("%s 服务器启动于 %s", scheme, ln.Addr())
+	return s.app.Listener(ln)
 }
 
-// Stop 优雅关闭服务器
+// Stop 优雅关闭服务器，停止接受新连接并等待处理中的请求完成，最多等待 server.shutdown_timeout
 func (s *Server) Stop() error {
-	log.Info("正在关闭 HTTP 服务器...")
-	return s.app.Shutdown()
+	timeout := s.cfg.Server.ShutdownTimeout.Duration()
+	log.WithField("timeout", timeout).Info("正在关闭 HTTP 服务器...")
+	servicev2.CloseStreams()
+	err := s.app.ShutdownWithTimeout(timeout)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("等待请求完成超时，%d 个未完成的连接将随进程退出关闭", s.app.Server().GetOpenConnectionsCount())
+	}
+	if s.cfg.Server.Socket != "" {
+		_ = os.Remove(s.cfg.Server.Socket)
+	}
+	return err
 }
@@ -1,8 +1,25 @@
 package servicev1
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"washwise/model"
 
-func RegisterRoutes(app fiber.Router) {
-	app.Get("/getLaundryMachines", GetLaundryMachines)
-	app.Get("/getMachineDetail", GetMachineDetail)
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler v1 接口处理函数
+type Handler struct {
+	machines model.MachineStore
+	usages   model.UsageStore
+}
+
+// NewHandler 创建 v1 接口处理函数
+func NewHandler(machines model.MachineStore, usages model.UsageStore) *Handler {
+	return &Handler{machines: machines, usages: usages}
+}
+
+func RegisterRoutes(app fiber.Router, machines model.MachineStore, usages model.UsageStore) {
+	h := NewHandler(machines, usages)
+
+	app.Get("/getLaundryMachines", h.GetLaundryMachines)
+	app.Get("/getMachineDetail", h.GetMachineDetail)
 }
@@ -1,30 +1,40 @@
 package servicev1
 
 import (
+	"encoding/json"
+	"slices"
 	"strconv"
 	"time"
+	"washwise/cache"
+	"washwise/config"
 	"washwise/model"
 	"washwise/util"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/sirupsen/logrus"
 )
 
 // @Summary 获取洗衣机列表
-// @Description 获取洗衣机列表
+// @Description 获取洗衣机列表，未配置的店铺返回404
 // @Tags v1
 // @Param LaundryID query string true "店铺ID"
 // @Produce json
 // @Success 200 {object} GetLaundryMachinesResp
 // @Router /api/v1/getLaundryMachines [get]
-func GetLaundryMachines(c *fiber.Ctx) error {
+func (h *Handler) GetLaundryMachines(c *fiber.Ctx) error {
 	shopId := c.Query("LaundryID")
 	if shopId == "" {
 		return util.BadRequest(c, "LaundryID is required")
 	}
-	machines, err := model.GetMachinesByShopID(shopId)
+	// 未配置的店铺不查询也不缓存，避免随意的 LaundryID 占用缓存
+	if !slices.Contains(config.Get().Shops, shopId) {
+		return util.NotFound(c, "shop not found")
+	}
+	// 只缓存机器数据，剩余时间在每次请求时计算
+	machines, err := cache.Load(shopId, "v1/machines", config.GetCacheTTL(), func() ([]model.Machine, error) {
+		return h.machines.GetMachinesByShopID(shopId)
+	})
 	if err != nil {
-		logrus.WithError(err).Error("db error")
+		util.RequestLogger(c).WithError(err).Error("db error")
 		return util.Internal(c)
 	}
 
@@ -33,24 +43,19 @@ func GetLaundryMachines(c *fiber.Ctx) error {
 	for _, machine := range machines {
 		k := strconv.FormatInt(machine.Id, 10)
 
-		remainTime := 0
-		predictUseTime := machine.AvgUseTime
-		if predictUseTime == 0 {
-			predictUseTime = 45 * 60 // 默认45分钟
-		}
-		if machine.Code == model.MachineCodeInUse {
-			remainTime = int(max(machine.LastUseTime+predictUseTime-time.Now().Unix(), 0))
-		}
 		resp.Data[k] = MachineInfo{
 			Name:       machine.Name,
 			DeviceCode: machine.Code,
 			DeviceMsg:  machine.Msg,
-			RemainTime: remainTime,
+			RemainTime: int(machine.PredictRemainTime()),
 			ErrorCount: 0,
 		}
 	}
-
-	return c.JSON(resp)
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return cache.Send(c, body, config.Get().Cache.MaxAge.Duration())
 }
 
 // @Summary 获取洗衣机详情
@@ -60,37 +65,27 @@ func GetLaundryMachines(c *fiber.Ctx) error {
 // @Produce json
 // @Success 200 {object} GetMachineDetailResp
 // @Router /api/v1/getMachineDetail [get]
-func GetMachineDetail(c *fiber.Ctx) error {
+func (h *Handler) GetMachineDetail(c *fiber.Ctx) error {
 	machineIdStr := c.Query("MachineID")
 	machineId, err := strconv.ParseInt(machineIdStr, 10, 64)
 	if err != nil {
 		return util.BadRequest(c, "MachineID is required")
 	}
 
-	now := time.Now()
 	resp := make(GetMachineDetailResp)
 
-	// 分7个区间依次查询并构建响应
-	for i := 6; i >= 0; i-- {
-		date := now.AddDate(0, 0, -i)
-		dateStr := date.Format("2006-01-02")
-
-		// 计算该天的起止时间
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, date.Location())
-
-		// 查询该天的使用次数
-		count, err := model.CountUsagesByMachineIDAndTimeRange(
-			machineId,
-			startOfDay.Unix(),
-			endOfDay.Unix(),
-		)
-		if err != nil {
-			logrus.WithError(err).Error("db error")
-			return util.Internal(c)
-		}
-
-		resp[dateStr] = int(count)
+	// 从每日汇总中一次读取近7天（洗衣房时区）的使用次数，没有使用的日期补0
+	days := model.RecentDays(time.Now(), 7)
+	rows, err := h.usages.GetDailyUsages(machineId, days[0], days[len(days)-1])
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return util.Internal(c)
+	}
+	for _, day := range days {
+		resp[day] = 0
+	}
+	for _, row := range rows {
+		resp[row.Day] = int(row.UsageCount)
 	}
 
 	return c.JSON(resp)
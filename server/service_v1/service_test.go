@@ -0,0 +1,61 @@
+package servicev1
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"washwise/config"
+	"washwise/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestGetMachineDetail(t *testing.T) {
+	prev := config.Get()
+	t.Cleanup(func() { config.Set(prev) })
+	cfg := config.Default()
+	cfg.Shops = []string{"shop"}
+	config.Set(cfg)
+	store := model.NewMemoryStore()
+	store.InsertMachinesIfNotExists([]model.Machine{{Id: 1, Name: "1号", ShopId: "shop"}})
+	now := time.Now().Unix()
+	store.CreateUsage(&model.Usage{MachineId: 1, StartTime: now, EndTime: now + 60})
+	store.CreateUsage(&model.Usage{MachineId: 1, StartTime: now - 30*86400, EndTime: now - 30*86400 + 60})
+
+	app := fiber.New()
+	RegisterRoutes(app.Group("/api/v1"), store, store)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/getMachineDetail?MachineID=1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := GetMachineDetailResp{}
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 7 || history[time.Now().Format("2006-01-02")] != 1 {
+		t.Errorf("expected 7 days with 1 usage today, got %v", history)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/getLaundryMachines?LaundryID=shop", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	machines := GetLaundryMachinesResp{}
+	if err := json.NewDecoder(resp.Body).Decode(&machines); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := machines.Data["1"]; !ok || len(machines.Data) != 1 {
+		t.Errorf("expected machine 1, got %v", machines.Data)
+	}
+
+	// 未配置的店铺不查询
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/getLaundryMachines?LaundryID=unknown", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("expected 404 for unknown shop, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,97 @@
+package servicev2
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"washwise/event"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	sseHeartbeat    = 15 * time.Second
+	sseWriteTimeout = 30 * time.Second
+)
+
+var (
+	// streamsClosed 关闭 HTTP 服务器时关闭，事件流和 WebSocket 连接随之结束
+	streamsClosed    = make(chan struct{})
+	closeStreamsOnce sync.Once
+)
+
+// CloseStreams 结束所有事件流和 WebSocket 连接，避免长连接阻塞 HTTP 服务器关闭
+// 事件总线此时仍可发布，后台任务可以在 HTTP 服务器关闭后再停止
+func CloseStreams() {
+	closeStreamsOnce.Do(func() { close(streamsClosed) })
+}
+
+// @Summary 事件流
+// @Description Server-Sent Events 推送机器状态变化；带设备标识时同时推送该设备的排队事件
+// @Tags v2
+// @Param shopId query string false "店铺ID"
+// @Param machineId query string false "洗衣机ID"
+// @Param deviceId query string false "设备标识，也可通过 X-Device-Id 请求头传递"
+// @Produce text/event-stream
+// @Success 200
+// @Failure 400 {object} CommonResp[any]
+// @Router /api/v2/events [get]
+func Events(c *fiber.Ctx) error {
+	req := &EventsReq{}
+	if err := c.QueryParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	filter := event.Filter{DeviceId: util.DeviceId(c)}
+	if req.ShopId != "" {
+		filter.ShopIds = []string{req.ShopId}
+	}
+	if req.MachineId != 0 {
+		filter.MachineIds = []int64{req.MachineId}
+	}
+	filter.All = len(filter.ShopIds) == 0 && len(filter.MachineIds) == 0
+	sub := event.Subscribe(filter)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+		ticker := time.NewTicker(sseHeartbeat)
+		defer ticker.Stop()
+
+		fmt.Fprint(w, "retry: 3000\n\n")
+		for {
+			// 流式响应持续时间不受服务器写超时限制，每次写入前续期
+			if err := conn.SetWriteDeadline(time.Now().Add(sseWriteTimeout)); err != nil {
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case e, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			case <-streamsClosed:
+				return
+			case <-ticker.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+		}
+	})
+	return nil
+}
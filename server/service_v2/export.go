@@ -0,0 +1,118 @@
+package servicev2
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"net"
+	"slices"
+	"strings"
+	"time"
+	"washwise/config"
+	"washwise/export"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const exportWriteTimeout = 30 * time.Second
+
+// isAdmin 请求是否携带了有效的管理员令牌
+func isAdmin(c *fiber.Ctx) bool {
+	token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !found || token == "" {
+		return false
+	}
+	for _, t := range config.Get().Admin.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// exportAuth 导出接口仅允许管理员访问，除非配置了 export.public
+func exportAuth(c *fiber.Ctx) error {
+	if config.Get().Export.Public || isAdmin(c) {
+		return c.Next()
+	}
+	if c.Get(fiber.HeaderAuthorization) == "" {
+		return fail(c, fiber.StatusUnauthorized, ErrCodeUnauthenticated, "admin token required")
+	}
+	return fail(c, fiber.StatusForbidden, ErrCodePermissionDenied, "invalid admin token")
+}
+
+// deadlineWriter 每批数据刷新前续期写超时，导出大量数据时不受服务器写超时限制
+type deadlineWriter struct {
+	*bufio.Writer
+	conn net.Conn
+}
+
+func (w deadlineWriter) Flush() error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil {
+		return err
+	}
+	return w.Writer.Flush()
+}
+
+// @Summary 导出使用记录
+// @Description 以 CSV 或 JSON Lines 格式流式导出使用记录，需要管理员令牌，配置 export.public 后公开
+// @Description 导出中途失败时状态码仍为200，最后一行为错误标记：CSV 为 `#error,export failed`，JSON Lines 为 `{"error":"export failed"}`
+// @Tags v2
+// @Param shopId query string false "店铺ID，默认全部店铺"
+// @Param from query string false "开始时间（含），YYYY-MM-DD 或 RFC3339"
+// @Param to query string false "结束时间，YYYY-MM-DD（含当天）或 RFC3339（不含）"
+// @Param format query string false "导出格式：csv（默认）或 jsonl"
+// @Param Authorization header string false "Bearer <管理员令牌>"
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Success 200
+// @Failure 400,401,403,404 {object} CommonResp[any]
+// @Router /api/v2/export/usages [get]
+func (h *Handler) ExportUsages(c *fiber.Ctx) error {
+	req := &ExportUsagesReq{}
+	if err := c.QueryParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+	if req.Format == "" {
+		req.Format = export.FormatCSV
+	}
+	if !slices.Contains(export.Formats, req.Format) {
+		return badRequest(c, "format must be one of "+strings.Join(export.Formats, ", "))
+	}
+	if req.ShopId != "" && !slices.Contains(config.Get().Shops, req.ShopId) {
+		return notFound(c, "shop not found")
+	}
+	from, err := export.ParseTime(req.From, false)
+	if err != nil {
+		return badRequest(c, "invalid from: "+err.Error())
+	}
+	to, err := export.ParseTime(req.To, true)
+	if err != nil {
+		return badRequest(c, "invalid to: "+err.Error())
+	}
+	if from > 0 && to > 0 && from >= to {
+		return badRequest(c, "from must be before to")
+	}
+
+	c.Set(fiber.HeaderContentType, export.ContentType(req.Format))
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="usages.`+req.Format+`"`)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	// 响应头发出后无法再返回错误，导出失败时记录日志，并在末尾写入错误标记
+	conn := c.Context().Conn()
+	store := h.usages
+	logger := util.RequestLogger(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		dw := deadlineWriter{Writer: w, conn: conn}
+		count, err := export.Usages(dw, req.Format, store, req.ShopId, from, to)
+		fields := logrus.Fields{"shopId": req.ShopId, "format": req.Format, "rows": count}
+		if err != nil {
+			logger.WithError(err).WithFields(fields).Error("导出使用记录失败")
+			_ = export.WriteError(dw, req.Format, "export failed")
+			return
+		}
+		logger.WithFields(fields).Info("导出使用记录完成")
+	})
+	return nil
+}
@@ -0,0 +1,260 @@
+package servicev2
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"washwise/config"
+	"washwise/model"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const maxFavoritesPerDevice = 50
+
+// @Summary 获取收藏列表
+// @Description 获取当前设备收藏的洗衣机和洗衣房
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Produce json
+// @Success 200 {object} CommonResp[GetFavoritesResp]
+// @Failure 400,500 {object} CommonResp[any]
+// @Router /api/v2/me/favorites [get]
+func GetFavorites(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+
+	favorites, err := model.GetFavoritesByDeviceID(deviceId)
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+
+	resp := &GetFavoritesResp{Items: make([]*FavoriteItem, 0, len(favorites))}
+	for i := range favorites {
+		resp.Items = append(resp.Items, newFavoriteItem(&favorites[i]))
+	}
+	return ok(c, resp)
+}
+
+// @Summary 添加收藏
+// @Description 收藏洗衣机（传 machineId）或整个洗衣房（只传 shopId），重复收藏返回已有记录
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Param body body FavoriteReq true "收藏目标"
+// @Accept json
+// @Produce json
+// @Success 200 {object} CommonResp[FavoriteItem]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/me/favorites [post]
+func (h *Handler) AddFavorite(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+
+	req := &FavoriteReq{}
+	if err := c.BodyParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	favorite := &model.Favorite{
+		DeviceId: deviceId,
+		ShopId:   req.ShopId,
+		Alias:    req.Alias,
+	}
+	if req.MachineId != 0 {
+		// 收藏洗衣机时以数据库中的洗衣房为准
+		machine, err := h.machines.GetMachineByID(req.MachineId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notFound(c, "machine not found")
+		} else if err != nil {
+			util.RequestLogger(c).WithError(err).Error("db error")
+			return internal(c)
+		}
+		favorite.MachineId = machine.Id
+		favorite.ShopId = machine.ShopId
+	} else if req.ShopId == "" {
+		return badRequest(c, "shopId or machineId is required")
+	} else if !slices.Contains(config.Get().Shops, req.ShopId) {
+		return notFound(c, "shop not found")
+	}
+
+	existing, err := model.FindFavorite(deviceId, favorite.ShopId, favorite.MachineId)
+	if err == nil {
+		return ok(c, newFavoriteItem(existing))
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+
+	favorites, err := model.GetFavoritesByDeviceID(deviceId)
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	if len(favorites) >= maxFavoritesPerDevice {
+		return fail(c, fiber.StatusBadRequest, ErrCodeLimitExceeded, "too many favorites")
+	}
+
+	if err := model.CreateFavorite(favorite); err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	return ok(c, newFavoriteItem(favorite))
+}
+
+// @Summary 修改收藏
+// @Description 修改收藏的备注名
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Param favoriteId path string true "收藏ID"
+// @Param body body UpdateFavoriteReq true "修改内容"
+// @Accept json
+// @Produce json
+// @Success 200 {object} CommonResp[FavoriteItem]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/me/favorites/{favoriteId} [put]
+func UpdateFavorite(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+	favoriteId, err := strconv.ParseInt(c.Params("favoriteId"), 10, 64)
+	if err != nil {
+		return badRequest(c, "favoriteId is required")
+	}
+
+	req := &UpdateFavoriteReq{}
+	if err := c.BodyParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	if _, err := model.UpdateFavoriteAlias(deviceId, favoriteId, req.Alias); err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+
+	favorite, err := model.GetFavorite(deviceId, favoriteId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound(c, "favorite not found")
+	} else if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	return ok(c, newFavoriteItem(favorite))
+}
+
+// @Summary 删除收藏
+// @Description 删除收藏
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Param favoriteId path string true "收藏ID"
+// @Produce json
+// @Success 200 {object} CommonResp[any]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/me/favorites/{favoriteId} [delete]
+func DeleteFavorite(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+	favoriteId, err := strconv.ParseInt(c.Params("favoriteId"), 10, 64)
+	if err != nil {
+		return badRequest(c, "favoriteId is required")
+	}
+
+	rows, err := model.DeleteFavorite(deviceId, favoriteId)
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	if rows == 0 {
+		return notFound(c, "favorite not found")
+	}
+	return success(c)
+}
+
+// @Summary 收藏概览
+// @Description 一次性获取所有收藏的洗衣机（及收藏洗衣房内全部洗衣机）的实时状态和预计剩余时间
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Produce json
+// @Success 200 {object} CommonResp[OverviewResp]
+// @Failure 400,500 {object} CommonResp[any]
+// @Router /api/v2/me/overview [get]
+func (h *Handler) GetOverview(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+
+	favorites, err := model.GetFavoritesByDeviceID(deviceId)
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+
+	// 按洗衣房归并收藏，保持收藏顺序
+	shopIds := make([]string, 0)
+	favoriteShops := make(map[string]bool)
+	favoriteMachines := make(map[int64]bool)
+	for _, favorite := range favorites {
+		if !slices.Contains(shopIds, favorite.ShopId) {
+			shopIds = append(shopIds, favorite.ShopId)
+		}
+		if favorite.MachineId == 0 {
+			favoriteShops[favorite.ShopId] = true
+		} else {
+			favoriteMachines[favorite.MachineId] = true
+		}
+	}
+
+	resp := &OverviewResp{Shops: make([]*OverviewShop, 0, len(shopIds))}
+	startDay, endDay := recentWeekRange()
+	for _, shopId := range shopIds {
+		machines, err := h.machines.GetMachinesWithUsageCount(shopId, startDay, endDay)
+		if err != nil {
+			util.RequestLogger(c).WithError(err).Error("db error")
+			return internal(c)
+		}
+
+		shop := &OverviewShop{
+			Id:       shopId,
+			Name:     model.GetShopName(shopId),
+			Favorite: favoriteShops[shopId],
+			Machines: make([]*GetMachinesRespItem, 0),
+		}
+		for i := range machines {
+			machine := &machines[i]
+			if !shop.Favorite && !favoriteMachines[machine.Id] {
+				continue
+			}
+			switch machine.Code {
+			case model.MachineCodeAvailable:
+				shop.Available++
+			case model.MachineCodeInUse:
+				shop.InUse++
+			}
+			shop.Machines = append(shop.Machines, newMachinesRespItem(machine))
+		}
+		resp.Shops = append(resp.Shops, shop)
+	}
+	return ok(c, resp)
+}
+
+func newFavoriteItem(favorite *model.Favorite) *FavoriteItem {
+	return &FavoriteItem{
+		Id:               favorite.Id,
+		ShopId:           favorite.ShopId,
+		ShopName:         model.GetShopName(favorite.ShopId),
+		MachineId:        favorite.MachineId,
+		Alias:            favorite.Alias,
+		CreatedAt:        favorite.CreatedAt,
+		CreatedAtRfc3339: model.FormatTime(favorite.CreatedAt),
+	}
+}
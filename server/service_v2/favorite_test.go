@@ -0,0 +1,90 @@
+package servicev2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"washwise/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// doJSONRequest 发送带 JSON 请求体的请求并解析统一响应
+func doJSONRequest[G any](t *testing.T, app *fiber.App, method, target, body string) (int, *CommonResp[G]) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	result := &CommonResp[G]{}
+	if err := json.Unmarshal(data, result); err != nil {
+		t.Fatalf("%s %s: invalid response %q", method, target, data)
+	}
+	return resp.StatusCode, result
+}
+
+func TestFavorites(t *testing.T) {
+	app, _ := newTestApp(t)
+	// 收藏保存在数据库中
+	if err := model.InitDB(model.DriverSQLite, filepath.Join(t.TempDir(), "washwise.db")); err != nil {
+		t.Fatal(err)
+	}
+	const target = "/api/v2/me/favorites?deviceId=device"
+
+	status, added := doJSONRequest[FavoriteItem](t, app, "POST", target, `{"machineId":2,"alias":"楼下"}`)
+	if status != fiber.StatusOK || added.Data.Id == 0 || added.Data.ShopId != testShopId || added.Data.Alias != "楼下" {
+		t.Fatalf("unexpected add response %d: %+v", status, added)
+	}
+
+	// 重复收藏返回已有记录
+	status, duplicate := doJSONRequest[FavoriteItem](t, app, "POST", target, `{"machineId":2}`)
+	if status != fiber.StatusOK || duplicate.Data.Id != added.Data.Id || duplicate.Data.Alias != "楼下" {
+		t.Errorf("expected existing favorite for duplicate, got %d: %+v", status, duplicate)
+	}
+	if status, _ := doJSONRequest[FavoriteItem](t, app, "POST", target, `{"shopId":"`+testShopId+`"}`); status != fiber.StatusOK {
+		t.Errorf("expected shop favorite to be added, got %d", status)
+	}
+
+	status, list := doRequest[GetFavoritesResp](t, app, "GET", target)
+	if status != fiber.StatusOK || len(list.Data.Items) != 2 || list.Data.Items[0].Id != added.Data.Id {
+		t.Fatalf("expected 2 favorites, got %d: %+v", status, list)
+	}
+	if status, other := doRequest[GetFavoritesResp](t, app, "GET", "/api/v2/me/favorites?deviceId=other"); status != fiber.StatusOK || len(other.Data.Items) != 0 {
+		t.Errorf("expected no favorites for another device, got %d: %+v", status, other)
+	}
+
+	item := fmt.Sprintf("/api/v2/me/favorites/%d?deviceId=device", added.Data.Id)
+	if status, resp := doRequest[any](t, app, "DELETE", fmt.Sprintf("/api/v2/me/favorites/%d?deviceId=other", added.Data.Id)); status != fiber.StatusNotFound || resp.Error != ErrCodeNotFound {
+		t.Errorf("expected other device to get 404, got %d: %+v", status, resp)
+	}
+	if status, _ := doRequest[any](t, app, "DELETE", item); status != fiber.StatusOK {
+		t.Errorf("expected delete to succeed, got %d", status)
+	}
+	if status, resp := doRequest[any](t, app, "DELETE", item); status != fiber.StatusNotFound || resp.Error != ErrCodeNotFound {
+		t.Errorf("expected deleting again to get 404, got %d: %+v", status, resp)
+	}
+	if _, list = doRequest[GetFavoritesResp](t, app, "GET", target); len(list.Data.Items) != 1 {
+		t.Errorf("expected 1 favorite after delete, got %+v", list.Data.Items)
+	}
+
+	for body, expected := range map[string]int{
+		`{}`:                fiber.StatusBadRequest,
+		`{"machineId":404}`: fiber.StatusNotFound,
+		`{"shopId":"nope"}`: fiber.StatusNotFound,
+		`not json`:          fiber.StatusBadRequest,
+	} {
+		if status, _ := doJSONRequest[any](t, app, "POST", target, body); status != expected {
+			t.Errorf("%s: expected %d, got %d", body, expected, status)
+		}
+	}
+	if status, _ := doJSONRequest[any](t, app, "POST", "/api/v2/me/favorites", `{"machineId":2}`); status != fiber.StatusBadRequest {
+		t.Errorf("expected missing device id to get 400, got %d", status)
+	}
+}
@@ -0,0 +1,272 @@
+package servicev2
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+	"washwise/config"
+	"washwise/model"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// 历史统计的时间粒度
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+	GranularityWeek = "week"
+)
+
+// granularity 时间粒度的区间划分方式，区间按请求时区的本地时间对齐
+type granularity struct {
+	truncate    func(t time.Time) time.Time // 所在区间的开始时间
+	next        func(t time.Time) time.Time // 下一个区间的开始时间，t 为区间开始时间
+	defaultDays int                         // 未指定开始时间时的默认统计天数
+	maxDays     int                         // 最大统计天数
+}
+
+var granularities = map[string]*granularity{
+	GranularityHour: {
+		truncate: func(t time.Time) time.Time {
+			// 按本地时间的分秒回退，兼容夏令时切换；每小时汇总按洗衣房时区的整点划分，
+			// 请求时区与洗衣房时区的整点需要一致，见 alignedZone
+			return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+		},
+		next:        func(t time.Time) time.Time { return t.Add(time.Hour) },
+		defaultDays: 1,
+		maxDays:     31,
+	},
+	GranularityDay: {
+		truncate:    localMidnight,
+		next:        func(t time.Time) time.Time { return localMidnight(t.AddDate(0, 0, 1)) },
+		defaultDays: 7,
+		maxDays:     366,
+	},
+	GranularityWeek: {
+		truncate: func(t time.Time) time.Time {
+			// 每周从周一开始
+			return localMidnight(t.AddDate(0, 0, -(int(t.Weekday())+6)%7))
+		},
+		next:        func(t time.Time) time.Time { return localMidnight(t.AddDate(0, 0, 7)) },
+		defaultDays: 56,
+		maxDays:     3 * 366,
+	},
+}
+
+// localMidnight 本地日期的零点，零点不存在（夏令时切换）时为当天最早的时间
+func localMidnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// historyRange 解析后的历史统计范围
+type historyRange struct {
+	granularity string
+	g           *granularity
+	loc         *time.Location
+	from, to    time.Time
+}
+
+// parseHistoryRange 解析统计范围参数，开始时间向前、结束时间向后对齐到区间边界
+func parseHistoryRange(req *HistoryReq, now time.Time) (*historyRange, error) {
+	r := &historyRange{granularity: req.Granularity, loc: model.Location()}
+	if r.granularity == "" {
+		r.granularity = GranularityDay
+	}
+	r.g = granularities[r.granularity]
+	if r.g == nil {
+		return nil, fmt.Errorf("granularity must be one of %s, %s, %s", GranularityHour, GranularityDay, GranularityWeek)
+	}
+	if req.Tz != "" {
+		loc, err := time.LoadLocation(req.Tz)
+		if err != nil {
+			return nil, fmt.Errorf("unknown tz %q", req.Tz)
+		}
+		r.loc = loc
+	}
+
+	var err error
+	r.to = now.In(r.loc)
+	if req.To != "" {
+		if r.to, err = parseHistoryTime(req.To, r.loc, true); err != nil {
+			return nil, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	r.from = r.to.AddDate(0, 0, -r.g.defaultDays)
+	if req.From != "" {
+		if r.from, err = parseHistoryTime(req.From, r.loc, false); err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+
+	r.from = r.g.truncate(r.from)
+	if start := r.g.truncate(r.to); start.Before(r.to) {
+		r.to = r.g.next(start)
+	}
+	if !r.from.Before(r.to) {
+		return nil, errors.New("from must be before to")
+	}
+	if !alignedZone(r.loc, r.from, r.to) {
+		return nil, fmt.Errorf("tz %s does not share whole hours with the campus timezone %s", r.loc, model.Location())
+	}
+	if r.to.After(r.from.AddDate(0, 0, r.g.maxDays)) {
+		return nil, fmt.Errorf("range exceeds %d days for granularity %s", r.g.maxDays, r.granularity)
+	}
+	return r, nil
+}
+
+// alignedZone 判断时区在范围两端的整点是否与洗衣房时区一致
+// 每小时汇总按洗衣房时区的整点划分，Asia/Kolkata 等相差非整小时的时区无法按汇总准确划分区间
+func alignedZone(loc *time.Location, times ...time.Time) bool {
+	for _, t := range times {
+		_, offset := t.In(loc).Zone()
+		_, campus := t.In(model.Location()).Zone()
+		if (offset-campus)%3600 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHistoryTime 解析 YYYY-MM-DD（按请求时区）或 RFC3339 格式的时间
+// 日期作为结束时间时取次日零点，使该日期整天包含在范围内
+func parseHistoryTime(value string, loc *time.Location, end bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(model.DayLayout, value, loc); err == nil {
+		if end {
+			t = localMidnight(t.AddDate(0, 0, 1))
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, errors.New("expected YYYY-MM-DD or RFC3339")
+	}
+	return t.In(loc), nil
+}
+
+// buildHistory 根据每小时汇总按区间累加，生成完整的区间序列，没有使用的区间计数为0
+func buildHistory(r *historyRange, rows []model.UsageHourly, machines int) *HistoryResp {
+	resp := &HistoryResp{
+		Granularity: r.granularity,
+		Timezone:    r.loc.String(),
+		From:        r.from.Format(time.RFC3339),
+		To:          r.to.Format(time.RFC3339),
+		Machines:    machines,
+		Buckets:     make([]*HistoryBucket, 0),
+	}
+
+	i := 0
+	for start := r.from; start.Before(r.to); {
+		end := r.g.next(start)
+		var count, busy int64
+		for ; i < len(rows) && rows[i].HourStart < end.Unix(); i++ {
+			if rows[i].HourStart >= start.Unix() {
+				count += rows[i].UsageCount
+				busy += rows[i].TotalDuration
+			}
+		}
+
+		utilization := 0.0
+		if capacity := end.Sub(start).Seconds() * float64(machines); capacity > 0 {
+			utilization = math.Min(math.Round(float64(busy)/capacity*1000)/10, 100)
+		}
+		resp.Buckets = append(resp.Buckets, &HistoryBucket{
+			Start:       start.Format(time.RFC3339),
+			End:         end.Format(time.RFC3339),
+			Count:       count,
+			BusyMinutes: busy / 60,
+			Utilization: utilization,
+		})
+		start = end
+	}
+	return resp
+}
+
+// history 查询机器在统计范围内的每小时汇总并生成区间序列
+func (h *Handler) history(c *fiber.Ctx, req *HistoryReq, machineIds []int64) error {
+	r, err := parseHistoryRange(req, time.Now())
+	if err != nil {
+		return badRequest(c, err.Error())
+	}
+	rows, err := h.usages.GetHourlyUsages(machineIds, r.from.Unix(), r.to.Unix())
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	return ok(c, buildHistory(r, rows, len(machineIds)))
+}
+
+// @Summary 获取洗衣机使用历史
+// @Description 按小时、天或周统计洗衣机在时间范围内的使用次数、使用时长和使用率，区间按指定时区对齐
+// @Tags v2
+// @Param machineId path string true "洗衣机ID"
+// @Param from query string false "开始时间（含），YYYY-MM-DD 或 RFC3339，默认按粒度取最近1天、7天或8周"
+// @Param to query string false "结束时间，YYYY-MM-DD（含当天）或 RFC3339（不含），默认当前时间"
+// @Param granularity query string false "时间粒度：hour（最长31天）、day（默认，最长366天）、week（最长1098天）"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，默认洗衣房时区（配置项 timezone），与洗衣房时区相差需为整小时"
+// @Produce json
+// @Success 200 {object} CommonResp[HistoryResp]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/machine/{machineId}/history [get]
+func (h *Handler) GetMachineHistory(c *fiber.Ctx) error {
+	machineId, err := strconv.ParseInt(c.Params("machineId"), 10, 64)
+	if err != nil {
+		return badRequest(c, "machineId is required")
+	}
+	req := &HistoryReq{}
+	if err := c.QueryParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	if _, err := h.machines.GetMachineByID(machineId); errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound(c, "machine not found")
+	} else if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	return h.history(c, req, []int64{machineId})
+}
+
+// @Summary 获取店铺使用历史
+// @Description 按小时、天或周统计店铺（可按机器类型筛选）所有机器的使用次数、使用时长和使用率
+// @Tags v2
+// @Param shopId path string true "店铺ID"
+// @Param type query string false "机器类型，如 洗衣机，默认全部类型"
+// @Param from query string false "开始时间（含），YYYY-MM-DD 或 RFC3339，默认按粒度取最近1天、7天或8周"
+// @Param to query string false "结束时间，YYYY-MM-DD（含当天）或 RFC3339（不含），默认当前时间"
+// @Param granularity query string false "时间粒度：hour（最长31天）、day（默认，最长366天）、week（最长1098天）"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，默认洗衣房时区（配置项 timezone），与洗衣房时区相差需为整小时"
+// @Produce json
+// @Success 200 {object} CommonResp[HistoryResp]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/shop/{shopId}/history [get]
+func (h *Handler) GetShopHistory(c *fiber.Ctx) error {
+	shopId := c.Params("shopId")
+	if !slices.Contains(config.Get().Shops, shopId) {
+		return notFound(c, "shop not found")
+	}
+	req := &HistoryReq{}
+	if err := c.QueryParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+
+	machines, err := h.machines.GetMachinesByShopID(shopId)
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	var machineIds []int64
+	for _, m := range machines {
+		if req.Type == "" || m.Type == req.Type {
+			machineIds = append(machineIds, m.Id)
+		}
+	}
+	if len(machineIds) == 0 {
+		return notFound(c, "no machines found")
+	}
+	return h.history(c, req, machineIds)
+}
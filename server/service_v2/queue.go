@@ -0,0 +1,162 @@
+package servicev2
+
+import (
+	"errors"
+	"washwise/model"
+	"washwise/queue"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary 加入排队
+// @Description 所有同类型机器都在使用时加入虚拟排队，轮到时通过事件流或 webhook 通知
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Param body body QueueReq true "排队目标"
+// @Accept json
+// @Produce json
+// @Success 200 {object} CommonResp[QueueStatusResp]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/queue [post]
+func JoinQueue(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+
+	req := &QueueReq{}
+	if err := c.BodyParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+	if req.ShopId == "" || req.Type == "" {
+		return badRequest(c, "shopId and type are required")
+	}
+	if req.Webhook != "" {
+		if err := queue.ValidateWebhook(req.Webhook); err != nil {
+			return badRequest(c, "invalid webhook")
+		}
+	}
+
+	position, err := queue.GetManager().Join(deviceId, req.ShopId, req.Type, req.Webhook)
+	if err != nil {
+		return queueError(c, err)
+	}
+	return ok(c, newQueueStatusResp(position))
+}
+
+// @Summary 获取排队状态
+// @Description 获取当前设备的排队位置和预计等待时间
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Param shopId query string true "店铺ID"
+// @Param type query string true "机器类型"
+// @Produce json
+// @Success 200 {object} CommonResp[QueueStatusResp]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/queue [get]
+func GetQueueStatus(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+
+	req := &QueueReq{}
+	if err := c.QueryParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+	if req.ShopId == "" || req.Type == "" {
+		return badRequest(c, "shopId and type are required")
+	}
+
+	position, err := queue.GetManager().Status(deviceId, req.ShopId, req.Type)
+	if err != nil {
+		return queueError(c, err)
+	}
+	return ok(c, newQueueStatusResp(position))
+}
+
+// @Summary 退出排队
+// @Description 退出排队
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Param shopId query string true "店铺ID"
+// @Param type query string true "机器类型"
+// @Produce json
+// @Success 200 {object} CommonResp[any]
+// @Failure 400,404,500 {object} CommonResp[any]
+// @Router /api/v2/queue [delete]
+func LeaveQueue(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+
+	req := &QueueReq{}
+	if err := c.QueryParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+	if req.ShopId == "" || req.Type == "" {
+		return badRequest(c, "shopId and type are required")
+	}
+
+	if err := queue.GetManager().Leave(deviceId, req.ShopId, req.Type); err != nil {
+		return queueError(c, err)
+	}
+	return success(c)
+}
+
+// @Summary 认领机器
+// @Description 收到轮到通知后，在宽限期内认领机器，逾期将让给下一位
+// @Tags v2
+// @Param X-Device-Id header string true "设备标识"
+// @Param body body QueueReq true "排队目标"
+// @Accept json
+// @Produce json
+// @Success 200 {object} CommonResp[any]
+// @Failure 400,404,409,500 {object} CommonResp[any]
+// @Router /api/v2/queue/claim [post]
+func ClaimQueue(c *fiber.Ctx) error {
+	deviceId := util.DeviceId(c)
+	if deviceId == "" {
+		return badRequest(c, "X-Device-Id is required")
+	}
+
+	req := &QueueReq{}
+	if err := c.BodyParser(req); err != nil {
+		return badRequest(c, err.Error())
+	}
+	if req.ShopId == "" || req.Type == "" {
+		return badRequest(c, "shopId and type are required")
+	}
+
+	if err := queue.GetManager().Claim(deviceId, req.ShopId, req.Type); err != nil {
+		return queueError(c, err)
+	}
+	return success(c)
+}
+
+func queueError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, queue.ErrNotInQueue), errors.Is(err, queue.ErrUnknownQueue):
+		return notFound(c, err.Error())
+	case errors.Is(err, queue.ErrNotYourTurn):
+		return fail(c, fiber.StatusConflict, ErrCodeFailedPrecondition, err.Error())
+	default:
+		util.RequestLogger(c).WithError(err).Error("queue error")
+		return internal(c)
+	}
+}
+
+func newQueueStatusResp(position *queue.Position) *QueueStatusResp {
+	return &QueueStatusResp{
+		EntryId:         position.Entry.Id,
+		ShopId:          position.Entry.ShopId,
+		Type:            position.Entry.Type,
+		Status:          queue.StatusName(position.Entry.Status),
+		Position:        position.Position,
+		Eta:             position.Eta,
+		ExpireAt:        position.Entry.ExpireAt,
+		ExpireAtRfc3339: model.FormatTime(position.Entry.ExpireAt),
+	}
+}
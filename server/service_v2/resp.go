@@ -0,0 +1,98 @@
+package servicev2
+
+import (
+	"errors"
+	"strings"
+	"washwise/util"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 机器可读的错误码，客户端应依据错误码而非 msg 处理错误
+const (
+	ErrCodeInvalidArgument     = "invalid_argument"     // 参数缺失或不合法
+	ErrCodeUnauthenticated     = "unauthenticated"      // 缺少身份凭证
+	ErrCodePermissionDenied    = "permission_denied"    // 身份凭证无效或无权访问
+	ErrCodeNotFound            = "not_found"            // 资源不存在
+	ErrCodeMethodNotAllowed    = "method_not_allowed"   // 请求方法不支持
+	ErrCodeFailedPrecondition  = "failed_precondition"  // 当前状态不允许该操作
+	ErrCodeLimitExceeded       = "limit_exceeded"       // 超出数量限制
+	ErrCodeRateLimited         = "rate_limited"         // 请求过于频繁，按 Retry-After 头等待后重试
+	ErrCodeUpgradeRequired     = "upgrade_required"     // 需要协议升级（WebSocket）
+	ErrCodeUpstreamUnavailable = "upstream_unavailable" // 上游洗衣平台不可用
+	ErrCodeInternal            = "internal"             // 服务内部错误
+)
+
+// statusErrCodes HTTP 状态码对应的默认错误码
+var statusErrCodes = map[int]string{
+	fiber.StatusBadRequest:          ErrCodeInvalidArgument,
+	fiber.StatusUnauthorized:        ErrCodeUnauthenticated,
+	fiber.StatusForbidden:           ErrCodePermissionDenied,
+	fiber.StatusNotFound:            ErrCodeNotFound,
+	fiber.StatusMethodNotAllowed:    ErrCodeMethodNotAllowed,
+	fiber.StatusConflict:            ErrCodeFailedPrecondition,
+	fiber.StatusUpgradeRequired:     ErrCodeUpgradeRequired,
+	fiber.StatusTooManyRequests:     ErrCodeRateLimited,
+	fiber.StatusBadGateway:          ErrCodeUpstreamUnavailable,
+	fiber.StatusServiceUnavailable:  ErrCodeUpstreamUnavailable,
+	fiber.StatusGatewayTimeout:      ErrCodeUpstreamUnavailable,
+	fiber.StatusInternalServerError: ErrCodeInternal,
+}
+
+// ok 返回成功响应
+func ok[G any](c *fiber.Ctx, data G) error {
+	return c.JSON(&CommonResp[G]{Data: data})
+}
+
+// success 返回不带数据的成功响应
+func success(c *fiber.Ctx) error {
+	return c.JSON(&CommonResp[any]{Msg: "success"})
+}
+
+// fail 返回错误响应
+func fail(c *fiber.Ctx, status int, errCode, msg string) error {
+	return c.Status(status).JSON(&CommonResp[any]{
+		Code:  status,
+		Error: errCode,
+		Msg:   msg,
+	})
+}
+
+func badRequest(c *fiber.Ctx, msg string) error {
+	return fail(c, fiber.StatusBadRequest, ErrCodeInvalidArgument, msg)
+}
+
+func notFound(c *fiber.Ctx, msg string) error {
+	return fail(c, fiber.StatusNotFound, ErrCodeNotFound, msg)
+}
+
+func internal(c *fiber.Ctx) error {
+	return fail(c, fiber.StatusInternalServerError, ErrCodeInternal, "Internal server error")
+}
+
+// IsV2Request 判断请求是否属于 v2 接口
+func IsV2Request(c *fiber.Ctx) bool {
+	return strings.HasPrefix(c.Path(), "/api/v2/") || c.Path() == "/api/v2"
+}
+
+// ErrorHandler 将 v2 接口中返回的错误（包括路由不存在、panic 等）转换为统一响应
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	msg := "Internal server error"
+	var e *fiber.Error
+	if errors.As(err, &e) {
+		status = e.Code
+		msg = e.Message
+	} else {
+		util.RequestLogger(c).WithError(err).Error("unhandled error")
+	}
+
+	errCode, ok := statusErrCodes[status]
+	if !ok {
+		errCode = ErrCodeInternal
+		if status < fiber.StatusInternalServerError {
+			errCode = ErrCodeInvalidArgument
+		}
+	}
+	return fail(c, status, errCode, msg)
+}
@@ -1,11 +1,47 @@
 package servicev2
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"washwise/model"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler v2 接口中需要访问机器和使用记录的处理函数
+type Handler struct {
+	machines model.MachineStore
+	usages   model.UsageStore
+}
+
+// NewHandler 创建 v2 接口处理函数
+func NewHandler(machines model.MachineStore, usages model.UsageStore) *Handler {
+	return &Handler{machines: machines, usages: usages}
+}
+
+func RegisterRoutes(r fiber.Router, machines model.MachineStore, usages model.UsageStore) {
+	h := NewHandler(machines, usages)
 
-func RegisterRoutes(r fiber.Router) {
 	r.Get("/shops", GetShops)
-	r.Get("/machines", GetMachines)
-	r.Get("/machine/:machineId", GetMachine)
-	r.Get("/machine/:machineId/like", Like)
-	r.Get("/machine/:machineId/dislike", DisLike)
+	r.Get("/machines", h.GetMachines)
+	r.Get("/shop/:shopId/history", h.GetShopHistory)
+	r.Get("/machine/:machineId", h.GetMachine)
+	r.Get("/machine/:machineId/history", h.GetMachineHistory)
+	r.Get("/machine/:machineId/like", h.Like)
+	r.Get("/machine/:machineId/dislike", h.DisLike)
+	r.Get("/events", Events)
+	r.Get("/ws", WebSocketUpgrade, websocket.New(WebSocket))
+
+	me := r.Group("/me")
+	me.Get("/favorites", GetFavorites)
+	me.Post("/favorites", h.AddFavorite)
+	me.Put("/favorites/:favoriteId", UpdateFavorite)
+	me.Delete("/favorites/:favoriteId", DeleteFavorite)
+	me.Get("/overview", h.GetOverview)
+
+	r.Post("/queue", JoinQueue)
+	r.Get("/queue", GetQueueStatus)
+	r.Delete("/queue", LeaveQueue)
+	r.Post("/queue/claim", ClaimQueue)
+
+	r.Get("/export/usages", exportAuth, h.ExportUsages)
 }
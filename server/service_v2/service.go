@@ -1,95 +1,108 @@
 package servicev2
 
 import (
+	"encoding/json"
+	"errors"
+	"slices"
 	"strconv"
 	"time"
+	"washwise/cache"
 	"washwise/config"
+	"washwise/cron"
 	"washwise/model"
 	"washwise/util"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
-var shops = map[string]string{
-	"202401041041470000069996565184": "沙河雁北洗衣房",
-	"202401041044000000069996552384": "沙河雁南洗衣房",
-	"202302071714530000012067133598": "海淀西土城校区",
-}
-
-// 暂无获取洗衣房名称的接口，先硬编码
-func getShopName(shopId string) string {
-	name, ok := shops[shopId]
-	if !ok {
-		return "未知洗衣房"
-	}
-	return name
-}
-
 // @Summary 获取店铺列表
 // @Description 获取店铺列表
 // @Tags v2
 // @Produce json
-// @Success 200 {object} GetShopsResp
+// @Success 200 {object} CommonResp[GetShopsResp]
+// @Failure 500 {object} CommonResp[any]
 // @Router /api/v2/shops [get]
 func GetShops(c *fiber.Ctx) error {
 	resp := &GetShopsResp{}
 	for _, shopId := range config.Get().Shops {
 		resp.Items = append(resp.Items, &GetShopsRespItem{
 			Id:   shopId,
-			Name: getShopName(shopId),
+			Name: model.GetShopName(shopId),
 		})
 	}
-	return c.JSON(resp)
+	return ok(c, resp)
 }
 
 // @Summary 获取洗衣机列表
-// @Description 获取洗衣机列表
+// @Description 获取洗衣机列表，店铺还没有机器数据且最近一次从洗衣平台获取失败时返回503 upstream_unavailable
 // @Tags v2
 // @Param shopId query string true "店铺ID"
 // @Produce json
-// @Success 200 {object} GetMachinesResp
+// @Param If-None-Match header string false "上次响应的 ETag，未变化时返回 304"
+// @Success 200 {object} CommonResp[GetMachinesResp]
+// @Success 304
+// @Failure 400,404,500,503 {object} CommonResp[any]
 // @Router /api/v2/machines [get]
-func GetMachines(c *fiber.Ctx) error {
+func (h *Handler) GetMachines(c *fiber.Ctx) error {
 	req := &GetMachinesReq{}
 	if err := c.QueryParser(req); err != nil {
-		return util.BadRequest(c, err.Error())
+		return badRequest(c, err.Error())
+	}
+	cfg := config.Get()
+	if !slices.Contains(cfg.Shops, req.ShopId) {
+		return notFound(c, "shop not found")
 	}
 
-	date := time.Now()
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()).AddDate(0, 0, -6)
-	endOfDay := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, date.Location())
-	machines, err := model.GetMachinesWithUsageCount(req.ShopId, startOfDay.Unix(), endOfDay.Unix())
+	// 使用次数按近7天统计，日期变化后使用新的缓存项
+	// 只缓存机器数据，剩余时间在每次请求时计算
+	startDay, endDay := recentWeekRange()
+	key := "v2/machines/" + startDay
+	machines, err := cache.Load(req.ShopId, key, config.GetCacheTTL(), func() ([]model.Machine, error) {
+		return h.machines.GetMachinesWithUsageCount(req.ShopId, startDay, endDay)
+	})
 	if err != nil {
-		logrus.WithError(err).Error("db error")
-		return util.Internal(c)
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	// 已有机器数据时照常返回，状态由机器详情任务更新
+	if len(machines) == 0 {
+		if tm := cron.GetTaskManager(); tm != nil {
+			if err := tm.FetchError(req.ShopId); err != nil {
+				util.RequestLogger(c).WithError(err).WithField("shopId", req.ShopId).Warn("洗衣平台不可用")
+				return fail(c, fiber.StatusServiceUnavailable, ErrCodeUpstreamUnavailable, "laundry platform unavailable")
+			}
+		}
 	}
 
 	resp := &GetMachinesResp{}
+	for i := range machines {
+		resp.Items = append(resp.Items, newMachinesRespItem(&machines[i]))
+	}
+	body, err := json.Marshal(&CommonResp[*GetMachinesResp]{Data: resp})
+	if err != nil {
+		return err
+	}
+	return cache.Send(c, body, cfg.Cache.MaxAge.Duration())
+}
 
-	for _, machine := range machines {
-
-		remainTime := int64(0)
-		if machine.Code == model.MachineCodeInUse {
-			predictUseTime := machine.AvgUseTime
-			if predictUseTime == 0 {
-				predictUseTime = 45 * 60 // 默认45分钟
-			}
-			remainTime = max(machine.LastUseTime+predictUseTime-time.Now().Unix(), 0)
-		}
+// recentWeekRange 返回洗衣房时区近7天（含今天）的起止日期
+func recentWeekRange() (string, string) {
+	days := model.RecentDays(time.Now(), 7)
+	return days[0], days[len(days)-1]
+}
 
-		resp.Items = append(resp.Items, &GetMachinesRespItem{
-			Id:         machine.Id,
-			Name:       machine.Name,
-			Type:       machine.Type,
-			Msg:        machine.Msg,
-			Status:     machine.Code,
-			UsageCount: machine.UsageCount,
-			RemainTime: remainTime,
-			Like:       machine.Like,
-		})
+func newMachinesRespItem(machine *model.Machine) *GetMachinesRespItem {
+	return &GetMachinesRespItem{
+		Id:         machine.Id,
+		Name:       machine.Name,
+		Type:       machine.Type,
+		Msg:        machine.Msg,
+		Status:     machine.Code,
+		UsageCount: machine.UsageCount,
+		RemainTime: machine.PredictRemainTime(),
+		Like:       machine.Like,
 	}
-	return c.JSON(resp)
 }
 
 // @Summary 获取洗衣机详情
@@ -97,73 +110,57 @@ func GetMachines(c *fiber.Ctx) error {
 // @Tags v2
 // @Param machineId path string true "洗衣机ID"
 // @Produce json
-// @Success 200 {object} MachineDetailResp
+// @Success 200 {object} CommonResp[MachineDetailResp]
+// @Failure 400,404,500 {object} CommonResp[any]
 // @Router /api/v2/machine/{machineId} [get]
-func GetMachine(c *fiber.Ctx) error {
+func (h *Handler) GetMachine(c *fiber.Ctx) error {
 	machineIdStr := c.Params("machineId")
 	machineId, err := strconv.ParseInt(machineIdStr, 10, 64)
 	if err != nil {
-		return util.BadRequest(c, "machineId is required")
+		return badRequest(c, "machineId is required")
 	}
 
 	// 获取机器信息
-	machine, err := model.GetMachineByID(machineId)
-	if err != nil {
-		logrus.WithError(err).Error("db error")
-		return util.Internal(c)
+	machine, err := h.machines.GetMachineByID(machineId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound(c, "machine not found")
+	} else if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
 	}
 
-	// 计算剩余时间
-	remainTime := int64(0)
-	if machine.Code == model.MachineCodeInUse {
-		predictUseTime := machine.AvgUseTime
-		if predictUseTime == 0 {
-			predictUseTime = 45 * 60 // 默认45分钟
-		}
-		remainTime = max(machine.LastUseTime+predictUseTime-time.Now().Unix(), 0)
+	// 获取近7天的使用历史，从每日汇总中一次读取
+	days := model.RecentDays(time.Now(), 7)
+	rows, err := h.usages.GetDailyUsages(machineId, days[0], days[len(days)-1])
+	if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
 	}
 
-	// 获取近7天的使用历史
-	now := time.Now()
 	history := make(map[string]int)
-
-	for i := 6; i >= 0; i-- {
-		date := now.AddDate(0, 0, -i)
-		dateStr := date.Format("2006-01-02")
-
-		// 计算该天的起止时间
-		startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-		endOfDay := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, date.Location())
-
-		// 查询该天的使用次数
-		count, err := model.CountUsagesByMachineIDAndTimeRange(
-			machineId,
-			startOfDay.Unix(),
-			endOfDay.Unix(),
-		)
-		if err != nil {
-			logrus.WithError(err).Error("db error")
-			return util.Internal(c)
-		}
-
-		history[dateStr] = int(count)
+	for _, day := range days {
+		history[day] = 0
+	}
+	for _, row := range rows {
+		history[row.Day] = int(row.UsageCount)
 	}
 
 	// 构建响应
 	resp := &MachineDetailResp{
-		Id:          machine.Id,
-		Name:        machine.Name,
-		Type:        machine.Type,
-		Msg:         machine.Msg,
-		Status:      machine.Code,
-		RemainTime:  remainTime,
-		Like:        machine.Like,
-		AvgUseTime:  machine.AvgUseTime,
-		LastUseTime: machine.LastUseTime,
-		History:     history,
-	}
-
-	return c.JSON(resp)
+		Id:                 machine.Id,
+		Name:               machine.Name,
+		Type:               machine.Type,
+		Msg:                machine.Msg,
+		Status:             machine.Code,
+		RemainTime:         machine.PredictRemainTime(),
+		Like:               machine.Like,
+		AvgUseTime:         machine.AvgUseTime,
+		LastUseTime:        machine.LastUseTime,
+		LastUseTimeRfc3339: model.FormatTime(machine.LastUseTime),
+		History:            history,
+	}
+
+	return ok(c, resp)
 }
 
 // @Summary 点赞洗衣机
@@ -171,21 +168,11 @@ func GetMachine(c *fiber.Ctx) error {
 // @Tags v2
 // @Param machineId path string true "洗衣机ID"
 // @Produce json
-// @Success 200
+// @Success 200 {object} CommonResp[any]
+// @Failure 400,404,500 {object} CommonResp[any]
 // @Router /api/v2/machine/{machineId}/like [get]
-func Like(c *fiber.Ctx) error {
-	machineIdStr := c.Params("machineId")
-	machineId, err := strconv.ParseInt(machineIdStr, 10, 64)
-	if err != nil {
-		return util.BadRequest(c, "machineId is required")
-	}
-
-	err = model.UpdateMachineLike(machineId, 1)
-	if err != nil {
-		logrus.WithError(err).Error("db error")
-		return util.Internal(c)
-	}
-	return util.Success(c)
+func (h *Handler) Like(c *fiber.Ctx) error {
+	return h.updateLike(c, 1)
 }
 
 // @Summary 点踩洗衣机
@@ -193,19 +180,36 @@ func Like(c *fiber.Ctx) error {
 // @Tags v2
 // @Param machineId path string true "洗衣机ID"
 // @Produce json
-// @Success 200
+// @Success 200 {object} CommonResp[any]
+// @Failure 400,404,500 {object} CommonResp[any]
 // @Router /api/v2/machine/{machineId}/dislike [get]
-func DisLike(c *fiber.Ctx) error {
-	machineIdStr := c.Params("machineId")
-	machineId, err := strconv.ParseInt(machineIdStr, 10, 64)
+func (h *Handler) DisLike(c *fiber.Ctx) error {
+	return h.updateLike(c, -1)
+}
+
+// updateLike 增减点赞数，并使机器所属商店的列表缓存失效
+func (h *Handler) updateLike(c *fiber.Ctx, value int64) error {
+	machineId, err := strconv.ParseInt(c.Params("machineId"), 10, 64)
 	if err != nil {
-		return util.BadRequest(c, "machineId is required")
+		return badRequest(c, "machineId is required")
 	}
 
-	err = model.UpdateMachineLike(machineId, -1)
+	machine, err := h.machines.GetMachineByID(machineId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound(c, "machine not found")
+	} else if err != nil {
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+
+	rows, err := h.machines.UpdateMachineLike(machineId, value)
 	if err != nil {
-		logrus.WithError(err).Error("db error")
-		return util.Internal(c)
+		util.RequestLogger(c).WithError(err).Error("db error")
+		return internal(c)
+	}
+	if rows == 0 {
+		return notFound(c, "machine not found")
 	}
-	return util.Success(c)
+	cache.Invalidate(machine.ShopId)
+	return success(c)
 }
@@ -0,0 +1,487 @@
+package servicev2
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"washwise/cache"
+	"washwise/config"
+	"washwise/cron"
+	"washwise/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testShopId = "shop"
+
+// newTestApp 使用内存数据创建测试服务
+func newTestApp(t *testing.T) (*fiber.App, *model.MemoryStore) {
+	t.Helper()
+	prev := config.Get()
+	t.Cleanup(func() { config.Set(prev) })
+	cfg := config.Default()
+	cfg.Shops = []string{testShopId}
+	config.Set(cfg)
+	cache.InvalidateAll()
+
+	store := model.NewMemoryStore()
+	now := time.Now().Unix()
+	if err := store.InsertMachinesIfNotExists([]model.Machine{
+		{Id: 1, Vendor: model.LegacyVendor, VendorId: 1, Name: "1号", ShopId: testShopId, Type: "洗衣机", Code: model.MachineCodeInUse, LastUseTime: now - 600, AvgUseTime: 2400},
+		{Id: 2, Vendor: model.LegacyVendor, VendorId: 2, Name: "2号", ShopId: testShopId, Type: "洗衣机", Code: model.MachineCodeAvailable},
+		{Id: 3, Vendor: model.LegacyVendor, VendorId: 3, Name: "3号", ShopId: "other", Type: "洗衣机"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, start := range []int64{now - 3600, now - 7200, now - 30*86400} {
+		if err := store.CreateUsage(&model.Usage{MachineId: 1, StartTime: start, EndTime: start + 2400}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler, DisableStartupMessage: true})
+	RegisterRoutes(app.Group("/api/v2"), store, store)
+	return app, store
+}
+
+// setConfig 复制当前配置修改后生效，测试结束时恢复，避免修改其他测试共享的配置
+func setConfig(t *testing.T, modify func(cfg *config.Config)) {
+	t.Helper()
+	prev := config.Get()
+	cfg := *prev
+	modify(&cfg)
+	config.Set(&cfg)
+	t.Cleanup(func() { config.Set(prev) })
+}
+
+// doRequest 发送请求并解析统一响应
+func doRequest[G any](t *testing.T, app *fiber.App, method, target string) (int, *CommonResp[G]) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(method, target, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	result := &CommonResp[G]{}
+	if err := json.Unmarshal(body, result); err != nil {
+		t.Fatalf("%s %s: invalid response %q", method, target, body)
+	}
+	return resp.StatusCode, result
+}
+
+func TestGetMachines(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	status, resp := doRequest[GetMachinesResp](t, app, "GET", "/api/v2/machines?shopId="+testShopId)
+	if status != fiber.StatusOK || len(resp.Data.Items) != 2 {
+		t.Fatalf("expected 2 machines, got status %d: %+v", status, resp)
+	}
+	item := resp.Data.Items[0]
+	if item.Id != 1 || item.UsageCount != 2 {
+		t.Errorf("expected machine 1 with 2 recent usages, got %+v", item)
+	}
+	if item.RemainTime < 1790 || item.RemainTime > 1800 {
+		t.Errorf("expected remain time about 1800s, got %d", item.RemainTime)
+	}
+
+	status, errResp := doRequest[any](t, app, "GET", "/api/v2/machines?shopId=other")
+	if status != fiber.StatusNotFound || errResp.Error != ErrCodeNotFound {
+		t.Errorf("expected not_found for unconfigured shop, got %d %+v", status, errResp)
+	}
+}
+
+func TestGetMachinesCache(t *testing.T) {
+	app, store := newTestApp(t)
+	target := "/api/v2/machines?shopId=" + testShopId
+
+	// 使用中机器的剩余时间每秒变化，响应体也随之变化，这里只验证数据未变化时的缓存
+	inUse, _ := store.GetMachineByID(1)
+	inUse.Code = model.MachineCodeAvailable
+	if err := store.UpdateMachine(inUse); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(etag string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		if etag != "" {
+			req.Header.Set(fiber.HeaderIfNoneMatch, etag)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := get("")
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if resp.StatusCode != fiber.StatusOK || etag == "" || resp.Header.Get(fiber.HeaderCacheControl) != "public, max-age=5" {
+		t.Fatalf("expected 200 with ETag and Cache-Control, got %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp := get(etag); resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("expected 304 for matching If-None-Match, got %d", resp.StatusCode)
+	}
+
+	// 数据变化后缓存失效前仍返回缓存的响应
+	machine, _ := store.GetMachineByID(2)
+	machine.Code = model.MachineCodeOffline
+	if err := store.UpdateMachine(machine); err != nil {
+		t.Fatal(err)
+	}
+	if resp := get(etag); resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("expected cached response before invalidation, got %d", resp.StatusCode)
+	}
+
+	cache.Invalidate(testShopId)
+	resp = get(etag)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderETag) == etag {
+		t.Errorf("expected new response after invalidation, got %d", resp.StatusCode)
+	}
+
+	// 点赞后列表中的点赞数变化，只有机器所属商店的缓存失效
+	builds := 0
+	loadOther := func() {
+		_, _ = cache.Load("other", "v2/test", time.Minute, func() (int, error) {
+			builds++
+			return builds, nil
+		})
+	}
+	loadOther()
+	etag = resp.Header.Get(fiber.HeaderETag)
+	if status, _ := doRequest[any](t, app, "GET", "/api/v2/machine/2/like"); status != fiber.StatusOK {
+		t.Fatalf("like: expected 200, got %d", status)
+	}
+	if resp := get(etag); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("expected new response after like, got %d", resp.StatusCode)
+	}
+	if loadOther(); builds != 1 {
+		t.Errorf("expected other shop to stay cached after like, got %d builds", builds)
+	}
+}
+
+func TestGetMachinesRemainTime(t *testing.T) {
+	app, _ := newTestApp(t)
+	target := "/api/v2/machines?shopId=" + testShopId
+
+	remain := func() int64 {
+		t.Helper()
+		_, resp := doRequest[GetMachinesResp](t, app, "GET", target)
+		for _, item := range resp.Data.Items {
+			if item.Id == 1 {
+				return item.RemainTime
+			}
+		}
+		t.Fatal("machine 1 not found")
+		return 0
+	}
+
+	// 缓存期间剩余时间仍按请求时间计算
+	first := remain()
+	time.Sleep(1100 * time.Millisecond)
+	if second := remain(); second >= first {
+		t.Errorf("expected remain time to count down while cached, got %d then %d", first, second)
+	}
+}
+
+func TestGetMachinesUpstreamUnavailable(t *testing.T) {
+	app, store := newTestApp(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Shops = []string{testShopId, "down"}
+		cfg.ShopVendors = map[string]string{testShopId: "campus", "down": "campus"}
+		cfg.Vendors = map[string]config.Vendor{"campus": {
+			Type:         "http_json",
+			BaseURL:      upstream.URL,
+			MachinesPath: "/shops/{shopId}/machines",
+			StatusPath:   "/machines/{machineId}",
+		}}
+	})
+	cron.InitTaskManager(store, store).RunOnce([]string{testShopId, "down"})
+
+	// 没有机器数据的店铺返回 503，已有数据的店铺照常返回
+	status, resp := doRequest[any](t, app, "GET", "/api/v2/machines?shopId=down")
+	if status != fiber.StatusServiceUnavailable || resp.Error != ErrCodeUpstreamUnavailable {
+		t.Errorf("expected 503 upstream_unavailable, got %d: %+v", status, resp)
+	}
+	if status, _ := doRequest[GetMachinesResp](t, app, "GET", "/api/v2/machines?shopId="+testShopId); status != fiber.StatusOK {
+		t.Errorf("expected shop with machines to return 200, got %d", status)
+	}
+}
+
+func TestGetMachine(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	status, resp := doRequest[MachineDetailResp](t, app, "GET", "/api/v2/machine/1")
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d: %+v", status, resp)
+	}
+	total := 0
+	for _, count := range resp.Data.History {
+		total += count
+	}
+	if len(resp.Data.History) != 7 || total != 2 {
+		t.Errorf("expected 7 days with 2 usages, got %v", resp.Data.History)
+	}
+
+	for target, expected := range map[string]int{
+		"/api/v2/machine/404": fiber.StatusNotFound,
+		"/api/v2/machine/abc": fiber.StatusBadRequest,
+	} {
+		if status, _ := doRequest[any](t, app, "GET", target); status != expected {
+			t.Errorf("%s: expected %d, got %d", target, expected, status)
+		}
+	}
+}
+
+func TestLike(t *testing.T) {
+	app, store := newTestApp(t)
+
+	for _, target := range []string{"/api/v2/machine/2/like", "/api/v2/machine/2/like", "/api/v2/machine/2/dislike"} {
+		if status, _ := doRequest[any](t, app, "GET", target); status != fiber.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, status)
+		}
+	}
+	machine, _ := store.GetMachineByID(2)
+	if machine.Like != 1 {
+		t.Errorf("expected like 1, got %d", machine.Like)
+	}
+
+	if status, _ := doRequest[any](t, app, "GET", "/api/v2/machine/404/like"); status != fiber.StatusNotFound {
+		t.Errorf("expected 404 for unknown machine, got %d", status)
+	}
+}
+
+func TestExportUsages(t *testing.T) {
+	app, _ := newTestApp(t)
+	token := "0123456789abcdef"
+	setConfig(t, func(cfg *config.Config) { cfg.Admin.Tokens = []string{token} })
+
+	export := func(target, auth string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := export("/api/v2/export/usages", ""); status != fiber.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", status)
+	}
+	if status, _ := export("/api/v2/export/usages", "wrong-token-value"); status != fiber.StatusForbidden {
+		t.Errorf("expected 403 with invalid token, got %d", status)
+	}
+
+	status, body := export("/api/v2/export/usages?shopId="+testShopId, token)
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if status != fiber.StatusOK || len(lines) != 4 {
+		t.Fatalf("expected header and 3 rows, got %d: %q", status, body)
+	}
+	if lines[0] != "machine_id,machine_name,machine_type,shop_id,start_time,end_time,duration,kind" ||
+		!strings.HasPrefix(lines[1], "1,1号,洗衣机,shop,") || !strings.HasSuffix(lines[1], ",2400,washer") {
+		t.Errorf("unexpected csv: %q", body)
+	}
+
+	// 公开导出时无需令牌，按时间范围过滤
+	setConfig(t, func(cfg *config.Config) { cfg.Export.Public = true })
+	from := time.Now().AddDate(0, 0, -1).Format(model.DayLayout)
+	status, body = export("/api/v2/export/usages?format=jsonl&from="+from, "")
+	lines = strings.Split(strings.TrimSpace(body), "\n")
+	record := map[string]any{}
+	if status != fiber.StatusOK || len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &record) != nil || record["kind"] != "washer" {
+		t.Errorf("expected 2 recent jsonl rows, got %d: %q", status, body)
+	}
+
+	for _, target := range []string{"/api/v2/export/usages?format=xml", "/api/v2/export/usages?from=yesterday"} {
+		if status, _ := doRequest[any](t, app, "GET", target); status != fiber.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, status)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	app, store := newTestApp(t)
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	model.SetLocation(loc)
+	t.Cleanup(func() { model.SetLocation(nil) })
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, loc).Unix()
+	for _, u := range []model.Usage{
+		{MachineId: 2, StartTime: base + 600, EndTime: base + 600 + 2400},
+		{MachineId: 2, StartTime: base + 3600, EndTime: base + 3600 + 1800},
+		{MachineId: 1, StartTime: base + 3300, EndTime: base + 3300 + 1200},
+		{MachineId: 2, StartTime: base + 86400, EndTime: base + 86400 + 3600},
+	} {
+		if err := store.CreateUsage(&u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, resp := doRequest[HistoryResp](t, app, "GET", "/api/v2/machine/2/history?from=2025-03-10&to=2025-03-11&tz=Asia/Shanghai")
+	if status != fiber.StatusOK || len(resp.Data.Buckets) != 2 {
+		t.Fatalf("expected 2 daily buckets, got %d: %+v", status, resp)
+	}
+	day := resp.Data.Buckets[0]
+	if day.Start != "2025-03-10T00:00:00+08:00" || day.Count != 2 || day.BusyMinutes != 70 || day.Utilization != 4.9 {
+		t.Errorf("unexpected daily bucket %+v", day)
+	}
+
+	status, resp = doRequest[HistoryResp](t, app, "GET", "/api/v2/shop/"+testShopId+"/history?granularity=hour&from=2025-03-10T09:30:00%2B08:00&to=2025-03-10T11:00:00%2B08:00&tz=Asia/Shanghai&type=洗衣机")
+	if status != fiber.StatusOK || len(resp.Data.Buckets) != 2 || resp.Data.Machines != 2 {
+		t.Fatalf("expected 2 hourly buckets for 2 machines, got %d: %+v", status, resp)
+	}
+	// 跨小时的使用时长拆分到两个小时，次数只计入开始的小时
+	if b := resp.Data.Buckets[0]; b.Start != "2025-03-10T09:00:00+08:00" || b.Count != 2 || b.BusyMinutes != 45 || b.Utilization != 37.5 {
+		t.Errorf("unexpected hourly bucket %+v", b)
+	}
+	if b := resp.Data.Buckets[1]; b.Count != 1 || b.BusyMinutes != 45 {
+		t.Errorf("unexpected hourly bucket %+v", b)
+	}
+
+	for target, expected := range map[string]int{
+		"/api/v2/machine/2/history?granularity=minute":                         fiber.StatusBadRequest,
+		"/api/v2/machine/2/history?tz=Mars/Olympus":                            fiber.StatusBadRequest,
+		"/api/v2/machine/2/history?tz=Asia/Kolkata":                            fiber.StatusBadRequest,
+		"/api/v2/machine/2/history?granularity=hour&from=2025-01-01":           fiber.StatusBadRequest,
+		"/api/v2/machine/2/history?from=2025-03-11&to=2025-03-10":              fiber.StatusBadRequest,
+		"/api/v2/machine/404/history":                                          fiber.StatusNotFound,
+		"/api/v2/shop/" + testShopId + "/history?type=" + "%E7%83%98%E5%B9%B2": fiber.StatusNotFound,
+	} {
+		if status, _ := doRequest[any](t, app, "GET", target); status != expected {
+			t.Errorf("%s: expected %d, got %d", target, expected, status)
+		}
+	}
+}
+
+func TestHistoryRangeDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// 2025-03-09 夏令时开始，当天只有23小时
+	r, err := parseHistoryRange(&HistoryReq{From: "2025-03-08", To: "2025-03-09", Tz: loc.String()}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := buildHistory(r, nil, 1)
+	if len(resp.Buckets) != 2 || resp.Buckets[1].Start != "2025-03-09T00:00:00-05:00" || resp.Buckets[1].End != "2025-03-10T00:00:00-04:00" {
+		t.Errorf("unexpected daily buckets across DST start: %+v %+v", resp.Buckets[0], resp.Buckets[1])
+	}
+
+	// 2025-11-02 夏令时结束，凌晨1点出现两次
+	r, err = parseHistoryRange(&HistoryReq{Granularity: GranularityHour, From: "2025-11-02", To: "2025-11-02T03:00:00-05:00", Tz: loc.String()}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = buildHistory(r, []model.UsageHourly{{HourStart: time.Date(2025, 11, 2, 6, 0, 0, 0, time.UTC).Unix(), UsageCount: 1, TotalDuration: 1800}}, 1)
+	if len(resp.Buckets) != 4 || resp.Buckets[1].Start != "2025-11-02T01:00:00-04:00" || resp.Buckets[2].Start != "2025-11-02T01:00:00-05:00" {
+		t.Fatalf("unexpected hourly buckets across DST end: %d buckets", len(resp.Buckets))
+	}
+	if resp.Buckets[2].Count != 1 || resp.Buckets[2].Utilization != 50 {
+		t.Errorf("expected usage in second 1am bucket, got %+v", resp.Buckets[2])
+	}
+
+	// 每周从周一开始
+	r, err = parseHistoryRange(&HistoryReq{Granularity: GranularityWeek, From: "2025-03-12", To: "2025-03-12", Tz: loc.String()}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.from.Format(model.DayLayout) != "2025-03-10" || r.to.Format(model.DayLayout) != "2025-03-17" {
+		t.Errorf("expected week 2025-03-10..2025-03-17, got %s..%s", r.from, r.to)
+	}
+}
+
+func TestGetMachineTimezone(t *testing.T) {
+	app, store := newTestApp(t)
+	t.Cleanup(func() { model.SetLocation(nil) })
+
+	// 洗衣房时区比 UTC 早14小时，日期边界不受进程时区影响
+	loc, err := time.LoadLocation("Pacific/Kiritimati")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.SetLocation(loc)
+	now := time.Now()
+	if err := store.UpdateMachine(&model.Machine{Id: 2, ShopId: testShopId, Type: "洗衣机", LastUseTime: now.Unix()}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, resp := doRequest[MachineDetailResp](t, app, "GET", "/api/v2/machine/2")
+	if status != fiber.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if _, ok := resp.Data.History[now.In(loc).Format(model.DayLayout)]; !ok {
+		t.Errorf("expected history to end at campus date, got %v", resp.Data.History)
+	}
+	if resp.Data.LastUseTimeRfc3339 != now.In(loc).Format(time.RFC3339) {
+		t.Errorf("expected lastUseTimeRfc3339 in +14:00, got %q", resp.Data.LastUseTimeRfc3339)
+	}
+}
+
+func TestQueueRequiredParams(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	for _, r := range []struct{ method, target string }{
+		{"GET", "/api/v2/queue?deviceId=d1"},
+		{"GET", "/api/v2/queue?deviceId=d1&shopId=" + testShopId},
+		{"DELETE", "/api/v2/queue?deviceId=d1&type=洗衣机"},
+		{"POST", "/api/v2/queue/claim?deviceId=d1"},
+	} {
+		status, resp := doRequest[any](t, app, r.method, r.target)
+		if status != fiber.StatusBadRequest || resp.Error != ErrCodeInvalidArgument {
+			t.Errorf("%s %s: expected 400 %s, got %d %q", r.method, r.target, ErrCodeInvalidArgument, status, resp.Error)
+		}
+	}
+}
+
+// failingExportStore 导出一批记录后失败
+type failingExportStore struct {
+	model.UsageStore
+}
+
+func (s failingExportStore) ExportUsages(shopId string, from, to int64, fn func(rows []model.UsageExportRow) error) error {
+	if err := fn([]model.UsageExportRow{{MachineId: 1, MachineName: "1号", StartTime: 1000, EndTime: 3400}}); err != nil {
+		return err
+	}
+	return errors.New("connection lost")
+}
+
+func TestExportUsagesFailure(t *testing.T) {
+	_, store := newTestApp(t)
+	setConfig(t, func(cfg *config.Config) { cfg.Export.Public = true })
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler, DisableStartupMessage: true})
+	RegisterRoutes(app.Group("/api/v2"), store, failingExportStore{store})
+
+	// 响应头已发出，中途失败时以错误标记结尾
+	for format, marker := range map[string]string{
+		"csv":   "#error,export failed",
+		"jsonl": `{"error":"export failed"}`,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v2/export/usages?format="+format, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		if resp.StatusCode != fiber.StatusOK || len(lines) < 2 || lines[len(lines)-1] != marker {
+			t.Errorf("%s: expected trailing error marker, got %d: %q", format, resp.StatusCode, body)
+		}
+	}
+}
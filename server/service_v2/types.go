@@ -1,9 +1,11 @@
 package servicev2
 
+// CommonResp v2 接口统一响应
 type CommonResp[G any] struct {
-	Code int    `json:"code"`
-	Msg  string `json:"msg,omitempty"`
-	Data G      `json:"data,omitempty"`
+	Code  int    `json:"code"`            // 成功为0，失败为 HTTP 状态码
+	Error string `json:"error,omitempty"` // 机器可读的错误码，见 ErrCode*
+	Msg   string `json:"msg,omitempty"`
+	Data  G      `json:"data,omitempty"`
 }
 
 type GetShopsResp struct {
@@ -34,6 +36,13 @@ type GetMachinesRespItem struct {
 	Like       int64  `json:"like"`
 }
 
+type ExportUsagesReq struct {
+	ShopId string `query:"shopId"`
+	From   string `query:"from"`
+	To     string `query:"to"`
+	Format string `query:"format"`
+}
+
 type MachineDetailResp struct {
 	Id         int64  `json:"id"`
 	Name       string `json:"name"`
@@ -43,7 +52,109 @@ type MachineDetailResp struct {
 	RemainTime int64  `json:"remainTime"`
 	Like       int64  `json:"like"`
 
-	AvgUseTime  int64          `json:"avgUseTime"`  // 平均使用时间，即预计使用时间，单位秒
-	LastUseTime int64          `json:"lastUseTime"` // 上个人开始使用时间
-	History     map[string]int `json:"history"`     // date -> usage count
+	AvgUseTime         int64          `json:"avgUseTime"`         // 平均使用时间，即预计使用时间，单位秒
+	LastUseTime        int64          `json:"lastUseTime"`        // 上个人开始使用时间，Unix 时间戳
+	LastUseTimeRfc3339 string         `json:"lastUseTimeRfc3339"` // 同 lastUseTime，洗衣房时区的 RFC3339 格式，未使用过时为空
+	History            map[string]int `json:"history"`            // date -> usage count
+}
+
+type HistoryReq struct {
+	From        string `query:"from"`
+	To          string `query:"to"`
+	Granularity string `query:"granularity"`
+	Tz          string `query:"tz"`
+	Type        string `query:"type"` // 机器类型，仅按店铺查询时有效
+}
+
+type HistoryResp struct {
+	Granularity string           `json:"granularity"`
+	Timezone    string           `json:"timezone"`
+	From        string           `json:"from"`     // 统计范围开始时间（含），RFC3339 格式
+	To          string           `json:"to"`       // 统计范围结束时间（不含），RFC3339 格式
+	Machines    int              `json:"machines"` // 参与统计的机器数
+	Buckets     []*HistoryBucket `json:"buckets"`
+}
+
+type HistoryBucket struct {
+	Start       string  `json:"start"` // 区间开始时间（含），RFC3339 格式
+	End         string  `json:"end"`   // 区间结束时间（不含），RFC3339 格式
+	Count       int64   `json:"count"`
+	BusyMinutes int64   `json:"busyMinutes"` // 区间内开始的使用的总时长，单位分钟
+	Utilization float64 `json:"utilization"` // 使用率百分比，即使用时长占区间时长×机器数的比例，最大100
+}
+
+type FavoriteReq struct {
+	ShopId    string `json:"shopId"`
+	MachineId int64  `json:"machineId"`
+	Alias     string `json:"alias"`
+}
+
+type UpdateFavoriteReq struct {
+	Alias string `json:"alias"`
+}
+
+type FavoriteItem struct {
+	Id               int64  `json:"id"`
+	ShopId           string `json:"shopId"`
+	ShopName         string `json:"shopName"`
+	MachineId        int64  `json:"machineId,omitempty"` // 为空表示收藏整个洗衣房
+	Alias            string `json:"alias"`
+	CreatedAt        int64  `json:"createdAt"`
+	CreatedAtRfc3339 string `json:"createdAtRfc3339"` // 同 createdAt，洗衣房时区的 RFC3339 格式
+}
+
+type GetFavoritesResp struct {
+	Items []*FavoriteItem `json:"items"`
+}
+
+type OverviewResp struct {
+	Shops []*OverviewShop `json:"shops"`
+}
+
+type OverviewShop struct {
+	Id        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Favorite  bool                   `json:"favorite"`  // 是否收藏了整个洗衣房
+	Available int                    `json:"available"` // 空闲机器数
+	InUse     int                    `json:"inUse"`     // 使用中机器数
+	Machines  []*GetMachinesRespItem `json:"machines"`
+}
+
+type QueueReq struct {
+	ShopId  string `json:"shopId" query:"shopId"`
+	Type    string `json:"type" query:"type"`
+	Webhook string `json:"webhook"` // 轮到时推送通知的地址，可选
+}
+
+type QueueStatusResp struct {
+	EntryId         int64  `json:"entryId"`
+	ShopId          string `json:"shopId"`
+	Type            string `json:"type"`
+	Status          string `json:"status"`          // waiting, notified
+	Position        int    `json:"position"`        // 前方等待人数+1，已通知时为0
+	Eta             int64  `json:"eta"`             // 预计轮到的剩余秒数，-1 表示无法预测
+	ExpireAt        int64  `json:"expireAt"`        // 已通知时的认领截止时间
+	ExpireAtRfc3339 string `json:"expireAtRfc3339"` // 同 expireAt，洗衣房时区的 RFC3339 格式，未通知时为空
+}
+
+type EventsReq struct {
+	ShopId    string `query:"shopId"`
+	MachineId int64  `query:"machineId"`
+}
+
+// WsClientMessage WebSocket 客户端消息
+type WsClientMessage struct {
+	Op         string   `json:"op"` // subscribe, unsubscribe, ping
+	ShopIds    []string `json:"shopIds"`
+	MachineIds []int64  `json:"machineIds"`
+}
+
+// WsServerMessage WebSocket 服务端控制消息，事件消息直接使用事件结构
+type WsServerMessage struct {
+	Type       string   `json:"type"` // hello, subscribed, pong, heartbeat, error
+	SessionId  string   `json:"sessionId,omitempty"`
+	Heartbeat  int      `json:"heartbeat,omitempty"` // 心跳间隔，单位秒
+	ShopIds    []string `json:"shopIds,omitempty"`
+	MachineIds []int64  `json:"machineIds,omitempty"`
+	Msg        string   `json:"msg,omitempty"`
 }
@@ -0,0 +1,204 @@
+package servicev2
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+	"washwise/event"
+	"washwise/util"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	wsHeartbeat     = 25 * time.Second
+	wsReadTimeout   = 2 * wsHeartbeat
+	wsWriteTimeout  = 10 * time.Second
+	wsSessionTTL    = 10 * time.Minute // 断线后保留订阅的时间，连接中随心跳续期
+	wsMaxReadSize   = 4096
+	wsMaxSubscribed = 200
+)
+
+// wsSession 断线重连后恢复订阅所需的会话
+type wsSession struct {
+	filter   event.Filter
+	expireAt time.Time
+}
+
+var (
+	wsSessions    = make(map[string]*wsSession)
+	wsSessionsMux sync.Mutex
+)
+
+// restoreSession 按会话ID恢复订阅，会话不存在或已过期时创建新会话
+func restoreSession(sessionId, deviceId string) (string, event.Filter) {
+	wsSessionsMux.Lock()
+	defer wsSessionsMux.Unlock()
+
+	now := time.Now()
+	for id, session := range wsSessions {
+		if session.expireAt.Before(now) {
+			delete(wsSessions, id)
+		}
+	}
+
+	if session, ok := wsSessions[sessionId]; ok && session.filter.DeviceId == deviceId {
+		session.expireAt = now.Add(wsSessionTTL)
+		return sessionId, session.filter
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	sessionId = hex.EncodeToString(b)
+	filter := event.Filter{DeviceId: deviceId}
+	wsSessions[sessionId] = &wsSession{filter: filter, expireAt: now.Add(wsSessionTTL)}
+	return sessionId, filter
+}
+
+// saveSession 保存会话的订阅并续期，连接断开后会话保留 wsSessionTTL
+func saveSession(sessionId string, filter event.Filter) {
+	wsSessionsMux.Lock()
+	defer wsSessionsMux.Unlock()
+	wsSessions[sessionId] = &wsSession{filter: filter, expireAt: time.Now().Add(wsSessionTTL)}
+}
+
+// WebSocketUpgrade 只允许 WebSocket 升级请求进入
+func WebSocketUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	c.Locals("deviceId", util.DeviceId(c))
+	return c.Next()
+}
+
+// @Summary WebSocket 推送
+// @Description 通过消息订阅洗衣房或洗衣机，接收状态变化和排队位置消息。
+// @Description 客户端消息：{"op":"subscribe|unsubscribe","shopIds":[],"machineIds":[]}、{"op":"ping"}；
+// @Description 重连时携带 hello 消息中的 sessionId 可恢复之前的订阅
+// @Tags v2
+// @Param sessionId query string false "会话ID"
+// @Param deviceId query string false "设备标识，用于接收排队消息"
+// @Success 101
+// @Failure 426 {object} CommonResp[any]
+// @Router /api/v2/ws [get]
+func WebSocket(conn *websocket.Conn) {
+	deviceId, _ := conn.Locals("deviceId").(string)
+	sessionId, filter := restoreSession(conn.Query("sessionId"), deviceId)
+	sub := event.Subscribe(filter)
+	defer sub.Close()
+	// 无论因何断开，都从断开时开始计算会话保留时间
+	defer func() { saveSession(sessionId, sub.Filter()) }()
+
+	var writeMux sync.Mutex
+	write := func(v any) error {
+		writeMux.Lock()
+		defer writeMux.Unlock()
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(v)
+	}
+
+	subscribed := func() *WsServerMessage {
+		f := sub.Filter()
+		return &WsServerMessage{Type: "subscribed", ShopIds: f.ShopIds, MachineIds: f.MachineIds}
+	}
+	if err := write(&WsServerMessage{Type: "hello", SessionId: sessionId, Heartbeat: int(wsHeartbeat.Seconds())}); err != nil {
+		return
+	}
+	if err := write(subscribed()); err != nil {
+		return
+	}
+
+	// 读取客户端消息，连接断开时结束
+	done := make(chan struct{})
+	conn.SetReadLimit(wsMaxReadSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	})
+	go func() {
+		defer close(done)
+		for {
+			msg := &WsClientMessage{}
+			if err := conn.ReadJSON(msg); err != nil {
+				return
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+
+			var reply *WsServerMessage
+			switch msg.Op {
+			case "subscribe", "unsubscribe":
+				f := sub.Filter()
+				f = updateFilter(f, msg)
+				if len(f.ShopIds)+len(f.MachineIds) > wsMaxSubscribed {
+					reply = &WsServerMessage{Type: "error", Msg: "too many subscriptions"}
+					break
+				}
+				sub.SetFilter(f)
+				saveSession(sessionId, f)
+				reply = subscribed()
+			case "ping":
+				reply = &WsServerMessage{Type: "pong"}
+			default:
+				reply = &WsServerMessage{Type: "error", Msg: "unknown op"}
+			}
+			if err := write(reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-streamsClosed:
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseServiceRestart, ""), time.Now().Add(wsWriteTimeout))
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseServiceRestart, ""), time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if err := write(&e); err != nil {
+				logrus.WithError(err).Debug("websocket write failed")
+				return
+			}
+		case <-ticker.C:
+			if err := write(&WsServerMessage{Type: "heartbeat"}); err != nil {
+				return
+			}
+			saveSession(sessionId, sub.Filter())
+			_ = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		}
+	}
+}
+
+// updateFilter 根据订阅消息增删订阅目标，设备标识保持不变
+func updateFilter(f event.Filter, msg *WsClientMessage) event.Filter {
+	shopIds := slices.Clone(f.ShopIds)
+	machineIds := slices.Clone(f.MachineIds)
+	if msg.Op == "subscribe" {
+		for _, id := range msg.ShopIds {
+			if id != "" && !slices.Contains(shopIds, id) {
+				shopIds = append(shopIds, id)
+			}
+		}
+		for _, id := range msg.MachineIds {
+			if id != 0 && !slices.Contains(machineIds, id) {
+				machineIds = append(machineIds, id)
+			}
+		}
+	} else {
+		shopIds = slices.DeleteFunc(shopIds, func(id string) bool { return slices.Contains(msg.ShopIds, id) })
+		machineIds = slices.DeleteFunc(machineIds, func(id int64) bool { return slices.Contains(msg.MachineIds, id) })
+	}
+	return event.Filter{DeviceId: f.DeviceId, ShopIds: shopIds, MachineIds: machineIds}
+}
@@ -0,0 +1,145 @@
+package servicev2
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"washwise/event"
+
+	"github.com/fasthttp/websocket"
+)
+
+// startWsServer 在随机端口启动测试服务，返回 WebSocket 地址
+func startWsServer(t *testing.T) string {
+	t.Helper()
+	app, _ := newTestApp(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return "ws://" + ln.Addr().String() + "/api/v2/ws"
+}
+
+func dialWs(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// readWs 读取下一条消息
+func readWs[G any](t *testing.T, conn *websocket.Conn) *G {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := new(G)
+	if err := json.Unmarshal(data, msg); err != nil {
+		t.Fatalf("invalid message %q", data)
+	}
+	return msg
+}
+
+// waitSession 等待服务端记录断开的会话
+func waitSession(t *testing.T, sessionId string) *wsSession {
+	t.Helper()
+	for range 100 {
+		wsSessionsMux.Lock()
+		session, ok := wsSessions[sessionId]
+		var copied wsSession
+		if ok {
+			copied = *session
+		}
+		wsSessionsMux.Unlock()
+		if ok {
+			return &copied
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("session %s not found", sessionId)
+	return nil
+}
+
+func TestWebSocketResume(t *testing.T) {
+	url := startWsServer(t) + "?deviceId=d1"
+
+	conn := dialWs(t, url)
+	hello := readWs[WsServerMessage](t, conn)
+	if hello.Type != "hello" || hello.SessionId == "" {
+		t.Fatalf("expected hello with session, got %+v", hello)
+	}
+	readWs[WsServerMessage](t, conn)
+
+	// 订阅和取消订阅
+	for _, msg := range []WsClientMessage{
+		{Op: "subscribe", ShopIds: []string{testShopId, "other"}, MachineIds: []int64{1}},
+		{Op: "unsubscribe", ShopIds: []string{"other"}},
+	} {
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatal(err)
+		}
+		readWs[WsServerMessage](t, conn)
+	}
+
+	event.Publish(event.Event{Type: event.TypeMachineStatus, ShopId: "other", MachineId: 3})
+	event.Publish(event.Event{Type: event.TypeMachineStatus, ShopId: testShopId, MachineId: 2})
+	if e := readWs[event.Event](t, conn); e.ShopId != testShopId || e.MachineId != 2 {
+		t.Errorf("expected event of subscribed shop only, got %+v", e)
+	}
+	_ = conn.Close()
+
+	// 断开后会话保留一段时间
+	session := waitSession(t, hello.SessionId)
+	if ttl := time.Until(session.expireAt); ttl <= 0 || ttl > wsSessionTTL {
+		t.Errorf("expected session to expire within %s, got %s", wsSessionTTL, ttl)
+	}
+
+	// 携带 sessionId 重连后恢复订阅
+	conn = dialWs(t, url+"&sessionId="+hello.SessionId)
+	if resumed := readWs[WsServerMessage](t, conn); resumed.SessionId != hello.SessionId {
+		t.Errorf("expected resumed session %s, got %s", hello.SessionId, resumed.SessionId)
+	}
+	subscribed := readWs[WsServerMessage](t, conn)
+	if len(subscribed.ShopIds) != 1 || subscribed.ShopIds[0] != testShopId || len(subscribed.MachineIds) != 1 {
+		t.Errorf("expected restored subscriptions, got %+v", subscribed)
+	}
+}
+
+func TestWebSocketSessionExpiry(t *testing.T) {
+	url := startWsServer(t) + "?deviceId=d1"
+
+	wsSessionsMux.Lock()
+	wsSessions["expired"] = &wsSession{filter: event.Filter{DeviceId: "d1", ShopIds: []string{testShopId}}, expireAt: time.Now().Add(-time.Second)}
+	wsSessionsMux.Unlock()
+
+	// 过期的会话被清理，重连得到新会话和空订阅
+	conn := dialWs(t, url+"&sessionId=expired")
+	if hello := readWs[WsServerMessage](t, conn); hello.SessionId == "expired" {
+		t.Error("expected a new session after expiry")
+	}
+	if subscribed := readWs[WsServerMessage](t, conn); len(subscribed.ShopIds) != 0 {
+		t.Errorf("expected no subscriptions, got %+v", subscribed)
+	}
+	wsSessionsMux.Lock()
+	_, ok := wsSessions["expired"]
+	wsSessionsMux.Unlock()
+	if ok {
+		t.Error("expected expired session to be removed")
+	}
+
+	// 其他设备不能恢复该会话
+	hello := readWs[WsServerMessage](t, dialWs(t, url))
+	other := dialWs(t, strings.Replace(url, "d1", "d2", 1)+"&sessionId="+hello.SessionId)
+	if resumed := readWs[WsServerMessage](t, other); resumed.SessionId == hello.SessionId {
+		t.Error("expected session not to be shared across devices")
+	}
+}
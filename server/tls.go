@@ -0,0 +1,84 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// certCheckInterval 检查证书文件是否更新的最短间隔
+const certCheckInterval = 10 * time.Second
+
+// certReloader 在 TLS 握手时按需检查证书文件，修改时间变化后重新加载
+// 重新加载失败（如证书和私钥只更新了一个）时继续使用旧证书，下次检查时重试
+type certReloader struct {
+	certFile string
+	keyFile  string
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader 加载证书，文件不存在或不匹配时返回错误
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// modTimes 获取证书和私钥文件的修改时间
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// load 读取证书和私钥
+func (r *certReloader) load() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return fmt.Errorf("读取证书文件失败: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("加载证书失败: %w", err)
+	}
+	r.cert, r.certMod, r.keyMod = &cert, certMod, keyMod
+	r.lastCheck = r.now()
+	return nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.now().Sub(r.lastCheck) >= certCheckInterval {
+		r.lastCheck = r.now()
+		certMod, keyMod, err := r.modTimes()
+		if err == nil && (!certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod)) {
+			if err := r.load(); err != nil {
+				log.WithError(err).Warn("重新加载 TLS 证书失败，继续使用旧证书")
+			} else {
+				log.WithField("cert", r.certFile).Info("TLS 证书已重新加载")
+			}
+		}
+	}
+	return r.cert, nil
+}
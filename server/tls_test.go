@@ -0,0 +1,104 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert 生成自签名证书并写入文件
+func writeCert(t *testing.T, certFile, keyFile, name string, mod time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDer},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func commonName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	base := time.Now().Add(-time.Hour)
+	writeCert(t, certFile, keyFile, "old.example.com", base)
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.lastCheck = now
+
+	writeCert(t, certFile, keyFile, "new.example.com", base.Add(time.Minute))
+	if name := commonName(t, r); name != "old.example.com" {
+		t.Errorf("expected old cert within check interval, got %s", name)
+	}
+
+	now = now.Add(certCheckInterval)
+	if name := commonName(t, r); name != "new.example.com" {
+		t.Errorf("expected reloaded cert, got %s", name)
+	}
+
+	// 私钥损坏时继续使用旧证书
+	if err := os.WriteFile(keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(certCheckInterval)
+	if name := commonName(t, r); name != "new.example.com" {
+		t.Errorf("expected previous cert after failed reload, got %s", name)
+	}
+}
+
+func TestNewCertReloaderMissing(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := newCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")); err == nil {
+		t.Error("expected error for missing files")
+	}
+}
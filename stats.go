@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"washwise/model"
+)
+
+// runStats 打印机器和使用记录统计
+func runStats(configPath string, args []string) error {
+	fs := newFlagSet("stats", &configPath)
+	_ = fs.Parse(args)
+
+	if _, err := setup(configPath); err != nil {
+		return err
+	}
+
+	machineStats, err := model.CountMachinesByShopAndCode()
+	if err != nil {
+		return err
+	}
+	fmt.Println("机器:")
+	fmt.Printf("  %-32s %-16s %6s %6s %6s %6s\n", "商店ID", "名称", "空闲", "使用中", "离线", "合计")
+	type row struct{ available, inUse, offline, total int64 }
+	rows := make(map[string]*row)
+	shopIds := make([]string, 0)
+	for _, stat := range machineStats {
+		r, ok := rows[stat.ShopId]
+		if !ok {
+			r = &row{}
+			rows[stat.ShopId] = r
+			shopIds = append(shopIds, stat.ShopId)
+		}
+		switch stat.Code {
+		case model.MachineCodeAvailable:
+			r.available += stat.Count
+		case model.MachineCodeInUse:
+			r.inUse += stat.Count
+		default:
+			r.offline += stat.Count
+		}
+		r.total += stat.Count
+	}
+	for _, shopId := range shopIds {
+		r := rows[shopId]
+		fmt.Printf("  %-32s %-16s %6d %6d %6d %6d\n", shopId, model.GetShopName(shopId), r.available, r.inUse, r.offline, r.total)
+	}
+
+	now := time.Now()
+	fmt.Println("使用记录:")
+	for _, period := range []struct {
+		name  string
+		since int64
+	}{
+		{"全部", 0},
+		{"近7天", now.AddDate(0, 0, -7).Unix()},
+		{"近24小时", now.Add(-24 * time.Hour).Unix()},
+	} {
+		count, err := model.CountUsagesSince(period.since)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %-10s %d\n", period.name, count)
+	}
+
+	queued, err := model.CountActiveQueueEntries()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("排队中: %d\n", queued)
+	return nil
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderDeviceId 客户端设备标识请求头，由客户端首次启动时生成并持久保存
+const HeaderDeviceId = "X-Device-Id"
+
+const maxDeviceIdLen = 64
+
+// DeviceId 从请求中读取设备标识，缺失或不合法时返回空字符串
+// 无法设置请求头的客户端（如 EventSource）可使用 deviceId 查询参数
+func DeviceId(c *fiber.Ctx) string {
+	id := strings.TrimSpace(c.Get(HeaderDeviceId))
+	if id == "" {
+		id = strings.TrimSpace(c.Query("deviceId"))
+	}
+	if id == "" || len(id) > maxDeviceIdLen {
+		return ""
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return ""
+		}
+	}
+	return id
+}
@@ -2,16 +2,14 @@ package util
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
-	"sync"
-	"time"
 
-	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
 
@@ -48,171 +46,135 @@ func (t *LogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-type entry struct {
-	k string
-	v any
-}
+// JSONLogFormatter 每条日志输出一行 JSON，固定字段 ts、level、caller、msg 在前，
+// 其余字段按名称排序，与固定字段重名时加 fields. 前缀
+type JSONLogFormatter struct{}
 
-// formatEntries 格式化并排序日志字段，保证稳定输出顺序
-func (t *LogFormatter) formatEntries(w io.Writer, data logrus.Fields) {
-	es := make([]entry, 0, len(data))
-	for k, v := range data {
-		es = append(es, entry{k: k, v: v})
-	}
-	sort.Slice(es, func(i, j int) bool { return strings.Compare(es[i].k, es[j].k) < 0 })
-	for _, e := range es {
-		fmt.Fprintf(w, " -%s[%v]", e.k, e.v)
-	}
-}
-
-// DailyRotateWriter 按日期自动轮换的文件写入器
-type DailyRotateWriter struct {
-	logDir          string
-	levelPrefix     string
-	currentDate     string
-	currentFilePath string
-	file            *os.File
-	mu              sync.Mutex
-}
-
-// NewDailyRotateWriter 创建一个新的按日期轮换的写入器
-func NewDailyRotateWriter(logDir, levelPrefix string) (*DailyRotateWriter, error) {
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, fmt.Errorf("创建日志目录失败: %w", err)
-	}
+// jsonLogKeys JSON 日志的固定字段
+var jsonLogKeys = []string{"ts", "level", "caller", "msg"}
 
-	writer := &DailyRotateWriter{
-		logDir:      logDir,
-		levelPrefix: levelPrefix,
-	}
-
-	if err := writer.rotate(); err != nil {
-		return nil, err
+// Format 实现 logrus.Formatter 接口
+func (f *JSONLogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	var b *bytes.Buffer
+	if entry.Buffer != nil {
+		b = entry.Buffer
+	} else {
+		b = &bytes.Buffer{}
 	}
 
-	return writer, nil
-}
-
-// Write 实现 io.Writer 接口
-func (w *DailyRotateWriter) Write(p []byte) (n int, err error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	// 检查日志文件
-	if err := w.checkFile(); err != nil {
-		return 0, err
+	b.WriteByte('{')
+	writeField := func(k string, v any) {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(v)
+		if err != nil {
+			// 无法序列化的字段按文本输出，不丢弃整条日志
+			value, _ = json.Marshal(fmt.Sprint(v))
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
 	}
 
-	return w.file.Write(p)
-}
-
-func (w *DailyRotateWriter) checkFile() error {
-	currentDate := time.Now().Format("20060102_15")
-
-	// 检查日期是否变化
-	if currentDate != w.currentDate {
-		return w.rotate()
+	writeField("ts", entry.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	writeField("level", entry.Level.String())
+	if entry.HasCaller() {
+		writeField("caller", fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line))
 	}
+	writeField("msg", entry.Message)
 
-	// 检查当前文件是否还存在
-	opened, err := w.file.Stat()
-	if err != nil {
-		return err
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
 	}
-	stat, err := os.Stat(w.currentFilePath)
-	if err != nil || !os.SameFile(stat, opened) {
-		return w.rotate()
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := k
+		if slices.Contains(jsonLogKeys, k) {
+			name = "fields." + k
+		}
+		writeField(name, entry.Data[k])
 	}
-
-	return nil
+	b.WriteString("}\n")
+	return b.Bytes(), nil
 }
 
-// rotate 轮换日志文件
-func (w *DailyRotateWriter) rotate() error {
-	currentDate := time.Now().Format("20060102_15")
-	filename := fmt.Sprintf("%s_%s.log", w.levelPrefix, currentDate)
-	filePath := filepath.Join(w.logDir, filename)
+type entry struct {
+	k string
+	v any
+}
 
-	// 关闭旧文件
-	if w.file != nil {
-		if err := w.file.Close(); err != nil {
-			return fmt.Errorf("关闭旧日志文件失败: %w", err)
-		}
+// formatEntries 格式化并排序日志字段，保证稳定输出顺序
+func (t *LogFormatter) formatEntries(w io.Writer, data logrus.Fields) {
+	es := make([]entry, 0, len(data))
+	for k, v := range data {
+		es = append(es, entry{k: k, v: v})
 	}
-
-	// 打开新文件
-	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return fmt.Errorf("打开日志文件失败: %w", err)
+	sort.Slice(es, func(i, j int) bool { return strings.Compare(es[i].k, es[j].k) < 0 })
+	for _, e := range es {
+		fmt.Fprintf(w, " -%s[%v]", e.k, e.v)
 	}
-
-	w.file = file
-	w.currentDate = currentDate
-	w.currentFilePath = filePath
-	return nil
 }
 
-// Close 关闭文件
-func (w *DailyRotateWriter) Close() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if w.file != nil {
-		return w.file.Close()
-	}
-	return nil
+// combinedLogPrefix 不按级别拆分时合并日志文件的前缀
+const combinedLogPrefix = "washwise"
+
+// levelFiles 按级别拆分时每个日志文件对应的级别
+var levelFiles = []struct {
+	prefix string
+	levels []logrus.Level
+}{
+	{"error", []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}},
+	{"warn", []logrus.Level{logrus.WarnLevel}},
+	{"info", []logrus.Level{logrus.InfoLevel}},
+	{"debug", []logrus.Level{logrus.DebugLevel}},
+	{"trace", []logrus.Level{logrus.TraceLevel}},
 }
 
-// LevelFileHook 不同级别的日志写入不同文件的Hook
+// LevelFileHook 将日志写入文件的Hook，可按级别写入不同文件或写入同一个文件
 type LevelFileHook struct {
-	writers map[logrus.Level]*DailyRotateWriter
+	writers map[logrus.Level]*RotateWriter
 	logDir  string
 }
 
-// NewLevelFileHook 创建一个新的级别文件Hook
-func NewLevelFileHook(logDir string) (*LevelFileHook, error) {
+// NewLevelFileHook 创建一个新的级别文件Hook，splitLevels 为 false 时所有级别写入同一个文件
+func NewLevelFileHook(logDir string, rotate RotateConfig, splitLevels bool) (*LevelFileHook, error) {
 	if logDir == "" {
 		logDir = defaultLogDir
 	}
 
 	hook := &LevelFileHook{
-		writers: make(map[logrus.Level]*DailyRotateWriter),
+		writers: make(map[logrus.Level]*RotateWriter),
 		logDir:  logDir,
 	}
 
-	// 为每个日志级别创建写入器
-	errorWriter, err := NewDailyRotateWriter(logDir, "error")
-	if err != nil {
-		return nil, fmt.Errorf("创建 error 级别的日志写入器失败: %w", err)
-	}
-	hook.writers[logrus.PanicLevel] = errorWriter
-	hook.writers[logrus.FatalLevel] = errorWriter
-	hook.writers[logrus.ErrorLevel] = errorWriter
-
-	warnWriter, err := NewDailyRotateWriter(logDir, "warn")
-	if err != nil {
-		return nil, fmt.Errorf("创建 warn 级别的日志写入器失败: %w", err)
-	}
-	hook.writers[logrus.WarnLevel] = warnWriter
-
-	infoWriter, err := NewDailyRotateWriter(logDir, "info")
-	if err != nil {
-		return nil, fmt.Errorf("创建 info 级别的日志写入器失败: %w", err)
-	}
-	hook.writers[logrus.InfoLevel] = infoWriter
-
-	debugWriter, err := NewDailyRotateWriter(logDir, "debug")
-	if err != nil {
-		return nil, fmt.Errorf("创建 debug 级别的日志写入器失败: %w", err)
+	if !splitLevels {
+		writer, err := NewRotateWriter(logDir, combinedLogPrefix, rotate)
+		if err != nil {
+			return nil, fmt.Errorf("创建日志写入器失败: %w", err)
+		}
+		for _, level := range logrus.AllLevels {
+			hook.writers[level] = writer
+		}
+		return hook, nil
 	}
-	hook.writers[logrus.DebugLevel] = debugWriter
 
-	traceWriter, err := NewDailyRotateWriter(logDir, "trace")
-	if err != nil {
-		return nil, fmt.Errorf("创建 trace 级别的日志写入器失败: %w", err)
+	// 为每个日志级别创建写入器
+	for _, file := range levelFiles {
+		writer, err := NewRotateWriter(logDir, file.prefix, rotate)
+		if err != nil {
+			_ = hook.Close()
+			return nil, fmt.Errorf("创建 %s 级别的日志写入器失败: %w", file.prefix, err)
+		}
+		for _, level := range file.levels {
+			hook.writers[level] = writer
+		}
 	}
-	hook.writers[logrus.TraceLevel] = traceWriter
-
 	return hook, nil
 }
 
@@ -241,7 +203,12 @@ func (hook *LevelFileHook) Fire(entry *logrus.Entry) error {
 
 // Close 关闭所有文件
 func (hook *LevelFileHook) Close() error {
+	closed := make(map[*RotateWriter]bool)
 	for _, writer := range hook.writers {
+		if closed[writer] {
+			continue
+		}
+		closed[writer] = true
 		if err := writer.Close(); err != nil {
 			return err
 		}
@@ -249,10 +216,21 @@ func (hook *LevelFileHook) Close() error {
 	return nil
 }
 
+// 日志格式
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 // LogConfig 日志配置
 type LogConfig struct {
-	Level string // 日志等级
-	Dir   string // 日志目录
+	Level  string // 日志等级
+	Dir    string // 日志目录
+	Format string // 日志格式，text 或 json，默认 text
+	Stdout bool   // 是否同时输出到标准输出
+
+	SplitLevels bool         // 是否按级别写入不同文件
+	Rotate      RotateConfig // 日志文件轮换配置
 }
 
 // ParseLogLevel 解析日志等级字符串
@@ -277,15 +255,29 @@ func ParseLogLevel(level string) logrus.Level {
 	}
 }
 
+// ApplyLogConfig 应用日志等级、格式和标准输出配置，可在运行时重复调用
+func ApplyLogConfig(config LogConfig) {
+	logrus.SetLevel(ParseLogLevel(config.Level))
+
+	if config.Format == LogFormatJSON {
+		logrus.SetFormatter(&JSONLogFormatter{})
+	} else {
+		logrus.SetFormatter(&LogFormatter{})
+	}
+
+	// 日志文件由 Hook 写入，标准输出默认屏蔽
+	if config.Stdout {
+		logrus.SetOutput(os.Stdout)
+	} else {
+		logrus.SetOutput(io.Discard)
+	}
+}
+
 // InitLogger 使用配置初始化日志系统
 func InitLogger(config LogConfig) {
 	// 设置基本配置
 	logrus.SetReportCaller(true)
-	logrus.SetFormatter(&LogFormatter{})
-
-	// 设置日志等级
-	level := ParseLogLevel(config.Level)
-	logrus.SetLevel(level)
+	ApplyLogConfig(config)
 
 	// 设置日志目录
 	logDir := config.Dir
@@ -294,7 +286,7 @@ func InitLogger(config LogConfig) {
 	}
 
 	// 创建文件Hook
-	fileHook, err := NewLevelFileHook(logDir)
+	fileHook, err := NewLevelFileHook(logDir, config.Rotate, config.SplitLevels)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "创建文件Hook失败: %v\n", err)
 		return
@@ -302,22 +294,4 @@ func InitLogger(config LogConfig) {
 
 	// 添加Hook
 	logrus.AddHook(fileHook)
-
-	// 屏蔽标准输出
-	logrus.SetOutput(io.Discard)
-}
-
-func FiberLogger() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		start := time.Now()
-		method := c.Method()
-		path := c.Path()
-		status := c.Response().StatusCode()
-
-		err := c.Next()
-		latency := float64(time.Since(start).Nanoseconds()) / 1000000.0
-
-		logrus.WithField("body", string(c.Response().Body())).Tracef("%d %8.2fms %s %s", status, latency, method, path)
-		return err
-	}
 }
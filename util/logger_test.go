@@ -1,7 +1,11 @@
 package util
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+
+	"github.com/sirupsen/logrus"
 )
 
 func TestLogger(t *testing.T) {
@@ -10,3 +14,27 @@ func TestLogger(t *testing.T) {
 		Dir:   "logs_test",
 	})
 }
+
+func TestJSONLogFormatter(t *testing.T) {
+	logger := logrus.New()
+	entry := logrus.NewEntry(logger).WithFields(logrus.Fields{
+		"machineId": 1,
+		"msg":       "conflict",
+		"error":     errors.New("boom"),
+	})
+	entry.Message = "测试"
+	entry.Level = logrus.WarnLevel
+
+	line, err := (&JSONLogFormatter{}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("invalid json %q: %v", line, err)
+	}
+	if got["level"] != "warning" || got["msg"] != "测试" || got["fields.msg"] != "conflict" ||
+		got["error"] != "boom" || got["machineId"] != float64(1) || got["ts"] == nil {
+		t.Errorf("unexpected fields: %s", line)
+	}
+}
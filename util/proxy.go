@@ -0,0 +1,96 @@
+package util
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrustedProxies 可信反向代理地址列表
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies 解析 IP 或 CIDR 列表，单个 IP 视为只包含该地址的网段
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("无法解析 IP 或 CIDR %q", value)
+		}
+		addr = addr.Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// Contains 判断地址是否属于可信代理
+func (p TrustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyConfig 确定客户端 IP 时使用的代理配置
+type proxyConfig struct {
+	proxies TrustedProxies
+	header  string
+}
+
+var proxies atomic.Pointer[proxyConfig]
+
+// SetTrustedProxies 设置可信代理及其传递客户端 IP 的请求头，ClientIP 使用
+func SetTrustedProxies(p TrustedProxies, header string) {
+	proxies.Store(&proxyConfig{proxies: p, header: header})
+}
+
+// ClientIP 获取请求的客户端 IP，直接连接的地址不是可信代理且不是 unix socket 时总是返回该地址
+// 请求头为 X-Forwarded-For 时从右向左查找第一个不可信的地址，左侧由客户端自行填写的部分不会被采用；
+// 其他请求头（如 X-Real-IP）按单个地址读取
+func ClientIP(c *fiber.Ctx) string {
+	remote, ok := netip.AddrFromSlice(c.Context().RemoteIP())
+	if !ok {
+		return c.IP()
+	}
+	remote = remote.Unmap()
+
+	// 通过 unix socket 连接的只能是本机的反向代理，视为可信
+	cfg := proxies.Load()
+	unix := c.Context().RemoteAddr().Network() == "unix"
+	if cfg == nil || (!unix && !cfg.proxies.Contains(remote)) {
+		return remote.String()
+	}
+	p := cfg.proxies
+
+	if !strings.EqualFold(cfg.header, fiber.HeaderXForwardedFor) {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(c.Get(cfg.header))); err == nil {
+			return addr.Unmap().String()
+		}
+		return remote.String()
+	}
+
+	client := remote
+	hops := strings.Split(c.Get(fiber.HeaderXForwardedFor), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !p.Contains(client) {
+			break
+		}
+	}
+	return client.String()
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestClientIP(t *testing.T) {
+	defer SetTrustedProxies(nil, "")
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString(ClientIP(c)) })
+	clientIP := func(xff string) string {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/", nil)
+		if xff != "" {
+			req.Header.Set(fiber.HeaderXForwardedFor, xff)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		return string(body[:n])
+	}
+
+	// 测试连接的地址为 0.0.0.0，未配置可信代理时忽略 X-Forwarded-For
+	SetTrustedProxies(nil, fiber.HeaderXForwardedFor)
+	if ip := clientIP("1.2.3.4"); ip != "0.0.0.0" {
+		t.Errorf("expected remote address without trusted proxies, got %s", ip)
+	}
+
+	proxies, err := ParseTrustedProxies([]string{"0.0.0.0", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTrustedProxies(proxies, fiber.HeaderXForwardedFor)
+	for xff, want := range map[string]string{
+		"":                           "0.0.0.0",
+		"1.2.3.4":                    "1.2.3.4",
+		"9.9.9.9, 1.2.3.4, 10.0.0.2": "1.2.3.4", // 最左侧由客户端伪造
+		"10.0.0.3, 10.0.0.2":         "10.0.0.3",
+		"bogus, 10.0.0.2":            "10.0.0.2",
+	} {
+		if ip := clientIP(xff); ip != want {
+			t.Errorf("X-Forwarded-For %q: expected %s, got %s", xff, want, ip)
+		}
+	}
+
+	// X-Real-IP 等请求头只包含一个地址
+	SetTrustedProxies(proxies, "X-Real-IP")
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Real-IP", "5.6.7.8")
+	req.Header.Set(fiber.HeaderXForwardedFor, "1.2.3.4")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	if ip := string(body[:n]); ip != "5.6.7.8" {
+		t.Errorf("expected X-Real-IP address, got %s", ip)
+	}
+
+	if _, err := ParseTrustedProxies([]string{"nginx"}); err == nil {
+		t.Error("expected error for invalid proxy")
+	}
+}
@@ -0,0 +1,102 @@
+package util
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// HeaderRequestId 请求标识头，客户端或反向代理传入时沿用，否则由服务端生成，并在响应中返回
+const HeaderRequestId = "X-Request-ID"
+
+const maxRequestIdLen = 128
+
+// requestIdKey 请求标识在 Locals 和 context 中的键
+type requestIdKey struct{}
+
+// validRequestId 判断传入的请求标识是否可以沿用，只允许可打印的 ASCII 字符，避免污染日志
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestId 获取当前请求的标识，未经过 AccessLog 中间件时返回空字符串
+func RequestId(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIdKey{}).(string)
+	return id
+}
+
+// RequestIdFromContext 从 context 中获取请求标识，context 来自 c.UserContext()
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// ContextLogger 带请求标识字段的日志，用于只能拿到 context 的代码
+func ContextLogger(ctx context.Context) *logrus.Entry {
+	if id := RequestIdFromContext(ctx); id != "" {
+		return logrus.WithField("requestId", id)
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// RequestLogger 带当前请求标识字段的日志，处理函数中记录日志时使用
+func RequestLogger(c *fiber.Ctx) *logrus.Entry {
+	return ContextLogger(c.UserContext())
+}
+
+// AccessLog 分配或沿用请求标识，并在处理完成后记录访问日志
+// 处理函数返回的错误在这里交给应用的 ErrorHandler，以便记录最终的状态码，因此应放在 recover 中间件之前
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		id := c.Get(HeaderRequestId)
+		if !validRequestId(id) {
+			id = utils.UUIDv4()
+		}
+		c.Locals(requestIdKey{}, id)
+		c.SetUserContext(context.WithValue(c.UserContext(), requestIdKey{}, id))
+		c.Set(HeaderRequestId, id)
+
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		fields := logrus.Fields{
+			"requestId": id,
+			"method":    c.Method(),
+			"route":     c.Route().Path,
+			"path":      c.Path(),
+			"status":    status,
+			"latencyMs": float64(time.Since(start).Microseconds()) / 1000,
+			"ip":        ClientIP(c),
+			"userAgent": c.Get(fiber.HeaderUserAgent),
+		}
+		// 流式响应（如事件流、导出）在中间件返回后才写出，长度未知
+		if !c.Response().IsBodyStream() {
+			fields["bytes"] = len(c.Response().Body())
+		}
+
+		entry := logrus.WithFields(fields)
+		if status >= fiber.StatusInternalServerError {
+			entry.Warnf("%s %s %d", c.Method(), c.Path(), status)
+		} else {
+			entry.Infof("%s %s %d", c.Method(), c.Path(), status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestAccessLog(t *testing.T) {
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	app := fiber.New()
+	app.Use(AccessLog())
+	app.Get("/machine/:machineId", func(c *fiber.Ctx) error {
+		RequestLogger(c).Info("handler")
+		if c.Params("machineId") == "0" {
+			return fiber.ErrNotFound
+		}
+		return c.SendString("ok")
+	})
+
+	// 沿用客户端传入的请求标识
+	req := httptest.NewRequest("GET", "/machine/1", nil)
+	req.Header.Set(HeaderRequestId, "abc-123")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get(HeaderRequestId); got != "abc-123" {
+		t.Errorf("expected request id abc-123, got %q", got)
+	}
+	entries := hook.AllEntries()
+	if len(entries) != 2 || entries[0].Data["requestId"] != "abc-123" {
+		t.Fatalf("expected handler log with request id, got %v", entries)
+	}
+	access := entries[1].Data
+	if access["route"] != "/machine/:machineId" || access["path"] != "/machine/1" ||
+		access["status"] != 200 || access["bytes"] != 2 || access["method"] != "GET" {
+		t.Errorf("unexpected access log fields %v", access)
+	}
+
+	// 返回错误时记录 ErrorHandler 写入的状态码，不合法的请求标识被替换
+	hook.Reset()
+	req = httptest.NewRequest("GET", "/machine/0", nil)
+	req.Header.Set(HeaderRequestId, "bad\nid")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := resp.Header.Get(HeaderRequestId)
+	if resp.StatusCode != fiber.StatusNotFound || id == "" || id == "bad\nid" {
+		t.Errorf("unexpected status %d or request id %q", resp.StatusCode, id)
+	}
+	if last := hook.LastEntry(); last == nil || last.Data["status"] != fiber.StatusNotFound || last.Data["requestId"] != id {
+		t.Errorf("unexpected access log %v", last)
+	}
+}
@@ -28,3 +28,17 @@ func Success(c *fiber.Ctx) error {
 		"msg":  "success",
 	})
 }
+
+func NotFound(c *fiber.Ctx, msg string) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"code": fiber.StatusNotFound,
+		"msg":  msg,
+	})
+}
+
+func TooManyRequests(c *fiber.Ctx, msg string) error {
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"code": fiber.StatusTooManyRequests,
+		"msg":  msg,
+	})
+}
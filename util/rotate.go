@@ -0,0 +1,290 @@
+package util
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志轮换周期
+const (
+	RotatePeriodHour = "hour"
+	RotatePeriodDay  = "day"
+)
+
+// rotatePeriodLayouts 轮换周期对应的文件名时间格式
+var rotatePeriodLayouts = map[string]string{
+	RotatePeriodHour: "20060102_15",
+	RotatePeriodDay:  "20060102",
+}
+
+// RotateConfig 日志轮换配置
+type RotateConfig struct {
+	Period   string        // 轮换周期，hour 或 day，默认 day
+	MaxSize  int64         // 单个文件最大字节数，超过后在同一周期内切换到新文件，0 表示不限
+	Compress bool          // 是否 gzip 压缩轮换后的文件
+	MaxAge   time.Duration // 轮换后的文件保留时长，0 表示不限
+	MaxFiles int           // 轮换后的文件最多保留个数，0 表示不限
+}
+
+// RotateWriter 按周期和大小轮换的日志文件写入器，文件名为 <prefix>_<时间>[.<序号>].log，
+// 轮换后的旧文件按配置压缩为 .log.gz，并清理超过保留时长或个数的文件
+type RotateWriter struct {
+	logDir string
+	prefix string
+	cfg    RotateConfig
+	now    func() time.Time // 时钟，测试时替换
+
+	mu              sync.Mutex
+	file            *os.File
+	currentFilePath string
+	currentPeriod   string
+	index           int   // 同一周期内按大小轮换的序号
+	size            int64 // 当前文件大小
+
+	sweeping sync.WaitGroup // 后台压缩和清理任务
+	sweepMu  sync.Mutex     // 同一时间只运行一次清理
+}
+
+// NewRotateWriter 创建日志文件写入器
+func NewRotateWriter(logDir, prefix string, cfg RotateConfig) (*RotateWriter, error) {
+	return newRotateWriter(logDir, prefix, cfg, time.Now)
+}
+
+func newRotateWriter(logDir, prefix string, cfg RotateConfig, now func() time.Time) (*RotateWriter, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+	if _, ok := rotatePeriodLayouts[cfg.Period]; !ok {
+		cfg.Period = RotatePeriodDay
+	}
+
+	w := &RotateWriter{
+		logDir: logDir,
+		prefix: prefix,
+		cfg:    cfg,
+		now:    now,
+	}
+	if err := w.rotate(0); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write 实现 io.Writer 接口
+func (w *RotateWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// 检查日志文件
+	if err := w.checkFile(int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotateWriter) period() string {
+	return w.now().Format(rotatePeriodLayouts[w.cfg.Period])
+}
+
+// checkFile 周期变化、写入后超过大小限制或当前文件被删除时切换文件
+func (w *RotateWriter) checkFile(incoming int64) error {
+	if w.period() != w.currentPeriod {
+		return w.rotate(0)
+	}
+	if w.cfg.MaxSize > 0 && w.size > 0 && w.size+incoming > w.cfg.MaxSize {
+		return w.rotate(w.index + 1)
+	}
+
+	// 检查当前文件是否还存在
+	opened, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(w.currentFilePath)
+	if err != nil || !os.SameFile(stat, opened) {
+		return w.rotate(w.index)
+	}
+	return nil
+}
+
+// filePath 指定周期和序号的日志文件路径
+func (w *RotateWriter) filePath(period string, index int) string {
+	if index == 0 {
+		return filepath.Join(w.logDir, fmt.Sprintf("%s_%s.log", w.prefix, period))
+	}
+	return filepath.Join(w.logDir, fmt.Sprintf("%s_%s.%d.log", w.prefix, period, index))
+}
+
+// rotate 从序号 index 开始切换到当前周期第一个未写满的文件，并在后台压缩和清理旧文件
+func (w *RotateWriter) rotate(index int) error {
+	period := w.period()
+
+	// 跳过已写满或已压缩的文件，重启后继续写入未写满的文件
+	var filePath string
+	var size int64
+	for ; ; index++ {
+		filePath = w.filePath(period, index)
+		if _, err := os.Stat(filePath + ".gz"); err == nil {
+			continue
+		}
+		size = 0
+		if info, err := os.Stat(filePath); err == nil {
+			size = info.Size()
+		}
+		if w.cfg.MaxSize <= 0 || size < w.cfg.MaxSize {
+			break
+		}
+	}
+
+	// 关闭旧文件
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("关闭旧日志文件失败: %w", err)
+		}
+		w.file = nil
+	}
+
+	// 打开新文件
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+
+	w.file = file
+	w.currentFilePath = filePath
+	w.currentPeriod = period
+	w.index = index
+	w.size = size
+
+	// 在切换时列出旧文件，后台任务不会处理之后才变为旧文件的当前文件
+	files, now := w.rotatedFiles(), w.now()
+	w.sweeping.Add(1)
+	go func() {
+		defer w.sweeping.Done()
+		w.sweep(files, now)
+	}()
+	return nil
+}
+
+// rotatedFiles 列出除当前文件外的日志文件
+func (w *RotateWriter) rotatedFiles() []string {
+	entries, err := os.ReadDir(w.logDir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(w.logDir, name)
+		if entry.IsDir() || path == w.currentFilePath || !strings.HasPrefix(name, w.prefix+"_") {
+			continue
+		}
+		if strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz") {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// rotatedFile 已轮换的日志文件
+type rotatedFile struct {
+	path    string
+	modTime time.Time
+}
+
+// sweep 压缩未压缩的旧日志文件，并删除超过保留时长或个数的文件
+func (w *RotateWriter) sweep(paths []string, now time.Time) {
+	w.sweepMu.Lock()
+	defer w.sweepMu.Unlock()
+
+	var files []rotatedFile
+	for _, path := range paths {
+		if w.cfg.Compress && strings.HasSuffix(path, ".log") {
+			if err := compressFile(path); err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					fmt.Fprintf(os.Stderr, "压缩日志文件 %s 失败: %v\n", path, err)
+				}
+				continue
+			}
+			path += ".gz"
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: path, modTime: info.ModTime()})
+	}
+
+	// 最新的文件在前
+	slices.SortFunc(files, func(a, b rotatedFile) int { return b.modTime.Compare(a.modTime) })
+	for i, f := range files {
+		expired := w.cfg.MaxAge > 0 && now.Sub(f.modTime) > w.cfg.MaxAge
+		if expired || (w.cfg.MaxFiles > 0 && i >= w.cfg.MaxFiles) {
+			_ = os.Remove(f.path)
+		}
+	}
+}
+
+// compressFile 将文件压缩为 .gz 并删除原文件，保留原文件的修改时间
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	err = errors.Join(err, gz.Close(), dst.Close())
+	if err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close 关闭文件，并等待后台压缩和清理完成
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.sweeping.Wait()
+	if w.file != nil {
+		err := w.file.Close()
+		w.file = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package util
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock 测试用时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func logFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestRotateWriter(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)}
+	w, err := newRotateWriter(dir, "info", RotateConfig{Period: RotatePeriodHour, MaxSize: 10, Compress: true}, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一小时内超过大小限制切换到 .1.log
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 下一小时切换到新文件，之前的文件被压缩
+	clock.t = clock.t.Add(time.Hour)
+	if _, err := w.Write([]byte("cccccc\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"info_20240301_10.1.log.gz", "info_20240301_10.log.gz", "info_20240301_11.log"}
+	if got := logFiles(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("expected files %v, got %v", want, got)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "info_20240301_10.1.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "bbbbbb\n" {
+		t.Errorf("unexpected rotated content %q", data)
+	}
+}
+
+func TestRotateWriterRetention(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 3, 10, 0, 0, 0, 0, time.Local)}
+
+	// 之前运行留下的日志，修改时间为对应日期
+	for day := 1; day <= 9; day++ {
+		date := time.Date(2024, 3, day, 12, 0, 0, 0, time.Local)
+		path := filepath.Join(dir, "error_"+date.Format("20060102")+".log")
+		if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, date, date); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 其他前缀的日志不受影响
+	other := filepath.Join(dir, "warn_20240301.log")
+	if err := os.WriteFile(other, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newRotateWriter(dir, "error", RotateConfig{
+		Period:   RotatePeriodDay,
+		MaxAge:   5 * 24 * time.Hour,
+		MaxFiles: 3,
+	}, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"error_20240307.log", "error_20240308.log", "error_20240309.log", "error_20240310.log", "warn_20240301.log"}
+	if got := logFiles(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("expected files %v, got %v", want, got)
+	}
+
+	// 按保留时长清理
+	w, err = newRotateWriter(dir, "error", RotateConfig{Period: RotatePeriodDay, MaxAge: 48 * time.Hour}, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range logFiles(t, dir) {
+		if strings.HasPrefix(name, "error_2024030") && name < "error_20240308" {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+}
+
+func TestLevelFileHookCombined(t *testing.T) {
+	dir := t.TempDir()
+	hook, err := NewLevelFileHook(dir, RotateConfig{Period: RotatePeriodDay}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close()
+
+	names := logFiles(t, dir)
+	if len(names) != 1 || !strings.HasPrefix(names[0], combinedLogPrefix+"_") {
+		t.Errorf("expected a single combined log file, got %v", names)
+	}
+}